@@ -1,6 +1,7 @@
 package comm
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/ansel1/merry"
@@ -18,13 +19,19 @@ type ParseResponseFunc = func(request, response []byte) error
 
 type NotifyFunc = func(Info)
 
+// FrameCompleteFunc определяет по принятой части ответа partial, что ответ на запрос request получен полностью.
+// Если complete == false, приём ответа продолжается до истечения таймаута окончания ответа
+type FrameCompleteFunc = func(request, partial []byte) (complete bool, err error)
+
 type Info struct {
+	Time     time.Time // момент начала ожидания ответа
 	Request  []byte
 	Response []byte
 	Err      error
 	Duration time.Duration
 	Port     string
 	Attempt  int
+	LockWait time.Duration // время ожидания освобождения порта, захваченного WithLockPort
 }
 
 type Config struct {
@@ -32,20 +39,34 @@ type Config struct {
 	TimeoutEndResponse time.Duration `json:"timeout_end_response" yaml:"timeout_end_response"` // таймаут окончания ответа
 	MaxAttemptsRead    int           `json:"max_attempts_read" yaml:"max_attempts_read"`       //число попыток получения ответа
 	Pause              time.Duration `json:"pause" yaml:"pause"`                               //пауза перед опросом
+	TotalTimeout       time.Duration `json:"total_timeout" yaml:"total_timeout"`               // общий таймаут всех попыток, 0 - не ограничен
+	TurnaroundDelay    time.Duration `json:"turnaround_delay" yaml:"turnaround_delay"`         // пауза после отправки запроса без ответа, см. T.Send
+	Echo               bool          `json:"echo" yaml:"echo"`                                 // порт возвращает эхо запроса перед ответом, например двухпроводный RS-485
 }
 
 var Err = merry.New("ошибка проткола последовательной приёмопередачи")
 
+// ErrEcho - эхо запроса при Config.Echo не совпадает с запросом, что указывает на коллизию на шине
+var ErrEcho = merry.New("искажено эхо запроса, возможна коллизия на шине").WithCause(Err)
+
 const (
 	LogKeyDuration = "время_ответа"
 	LogKeyAttempt  = "число_попыток"
 )
 
 type T struct {
-	cfg  Config
-	rw   io.ReadWriter
-	prs  ParseResponseFunc
-	port string
+	cfg           Config
+	rw            io.ReadWriter
+	prs           ParseResponseFunc
+	frameComplete FrameCompleteFunc
+	notify        *Dispatcher
+	recorder      *Recorder
+	retryPolicy   RetryPolicy
+	logEnabled    *bool
+	port          string
+	priority      Priority
+	statsDevice   DeviceKey
+	lockWait      time.Duration // время ожидания порта текущей транзакцией
 }
 
 func New(rw io.ReadWriter, cfg Config) T {
@@ -63,6 +84,18 @@ func (x T) WithLockPort(port string) T {
 	return x
 }
 
+// WithPriority задаёт приоритет транзакций при ожидании порта, захваченного WithLockPort
+func (x T) WithPriority(p Priority) T {
+	x.priority = p
+	return x
+}
+
+// WithStatsDevice задаёт устройство и функцию протокола, по которым ведётся статистика Stats
+func (x T) WithStatsDevice(device, function string) T {
+	x.statsDevice = DeviceKey{Device: device, Function: function}
+	return x
+}
+
 func (x T) WithReadWriter(rw io.ReadWriter) T {
 	x.rw = rw
 	return x
@@ -73,6 +106,54 @@ func (x T) WithConfig(cfg Config) T {
 	return x
 }
 
+// WithNotify задаёт функцию уведомления о приёмопередаче данного экземпляра.
+// Функция, заданная SetNotify, также вызывается.
+// При переполнении очереди транзакция ожидает доставки уведомлений, см. WithNotifyDispatcher
+func (x T) WithNotify(f NotifyFunc) T {
+	if f == nil {
+		return x.WithNotifyDispatcher(nil)
+	}
+	return x.WithNotifyDispatcher(NewDispatcher(f, DefaultNotifyQueueSize, DefaultOverflowPolicy))
+}
+
+// WithNotifyDispatcher задаёт очередь уведомлений о приёмопередаче данного экземпляра
+func (x T) WithNotifyDispatcher(d *Dispatcher) T {
+	x.notify = d
+	return x
+}
+
+// WithRecorder задаёт запись приёмопередачи данного экземпляра.
+// Recorder, заданный SetRecorder, также используется
+func (x T) WithRecorder(r *Recorder) T {
+	x.recorder = r
+	return x
+}
+
+// WithLogEnabled включает или отключает логгирование приёмопередачи данного экземпляра
+// независимо от значения, заданного SetEnableLog
+func (x T) WithLogEnabled(enable bool) T {
+	x.logEnabled = &enable
+	return x
+}
+
+// WithRetryPolicy задаёт политику повтора запроса после неудачной попытки.
+// По умолчанию запрос повторяется после таймаута и после ошибок протокола Err
+func (x T) WithRetryPolicy(p RetryPolicy) T {
+	x.retryPolicy = p
+	return x
+}
+
+// WithFrameComplete задаёт функцию, позволяющую завершить приём ответа, не дожидаясь таймаута окончания ответа
+func (x T) WithFrameComplete(f FrameCompleteFunc) T {
+	x.frameComplete = f
+	return x
+}
+
+// FrameComplete возвращает функцию, заданную WithFrameComplete, либо nil
+func (x T) FrameComplete() FrameCompleteFunc {
+	return x.frameComplete
+}
+
 func (x T) WithAppendParse(prs ParseResponseFunc) T {
 	xPrs := x.prs
 	x.prs = func(request, response []byte) error {
@@ -106,10 +187,29 @@ func (x T) WithPrependParse(prs ParseResponseFunc) T {
 }
 
 func (x T) GetResponse(log Logger, ctx context.Context, request []byte) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if x.cfg.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, x.cfg.TotalTimeout)
+		defer cancel()
+	}
 
-	x.lockPort()
-	response, err := x.getResponse(log, ctx, request)
-	x.unlockPort()
+	lockWait, err := x.lockPort(ctx)
+	if err != nil {
+		x.recordTransactionStats(err)
+		return nil, merry.Appendf(err, "запрорс % X", request)
+	}
+	x.lockWait = lockWait
+	response, attempts, err := x.getResponse(log, ctx, request)
+	if errUnlock := x.unlockPort(); errUnlock != nil {
+		if err == nil {
+			return response, errUnlock
+		}
+		err = merry.Appendf(err, "%v", errUnlock)
+	}
+	x.recordTransactionStats(err)
 
 	if err == nil {
 		return response, nil
@@ -122,22 +222,63 @@ func (x T) GetResponse(log Logger, ctx context.Context, request []byte) ([]byte,
 			panic("unexpected")
 		}
 	}
+	if x.cfg.TotalTimeout > 0 && ctxErr(ctx) == context.DeadlineExceeded {
+		err = merry.Appendf(err, "превышен общий таймаут %v", x.cfg.TotalTimeout)
+	}
 	err = merry.Appendf(err, "запрорс % X", request).
-		Appendf("таймаут ожидания ответа %v, таймаут окончания ответа %v, %d повторов",
+		Appendf("таймаут ожидания ответа %v, таймаут окончания ответа %v",
 			x.cfg.TimeoutGetResponse,
-			x.cfg.TimeoutEndResponse,
-			x.cfg.MaxAttemptsRead)
+			x.cfg.TimeoutEndResponse).
+		Appendf("выполнено попыток %d из %d", attempts, x.cfg.MaxAttemptsRead)
 	if len(response) > 0 {
 		err = merry.Appendf(err, "ответ % X", response)
 	}
 	return response, err
 }
 
+// Send отправляет запрос, на который не ожидается ответ, например широковещательный.
+// Перед отправкой выдерживается пауза Config.Pause, после отправки порт удерживается
+// в течение Config.TurnaroundDelay, чтобы устройства успели обработать запрос
+func (x T) Send(log Logger, ctx context.Context, request []byte) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if x.cfg.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, x.cfg.TotalTimeout)
+		defer cancel()
+	}
+
+	lockWait, err := x.lockPort(ctx)
+	if err != nil {
+		x.recordTransactionStats(err)
+		return merry.Appendf(err, "запрорс % X", request)
+	}
+	x.lockWait = lockWait
+	err = x.send(log, ctx, request)
+	if errUnlock := x.unlockPort(); errUnlock != nil {
+		if err == nil {
+			err = errUnlock
+		} else {
+			err = merry.Appendf(err, "%v", errUnlock)
+		}
+	}
+	x.recordTransactionStats(err)
+
+	if err == nil {
+		return nil
+	}
+	if merry.Is(err, context.Canceled) {
+		err = merry.Append(err, "отправка прервана")
+	}
+	return merry.Appendf(err, "запрорс % X", request)
+}
+
 func Write(ctx context.Context, request []byte, rw io.Writer, cfg Config) error {
 
 	t := time.Now()
 	writtenCount, err := rw.Write(request)
-	for ; err == nil && writtenCount == 0 &&
+	for ; err == nil && writtenCount == 0 && ctx.Err() == nil &&
 		time.Since(t) < cfg.TimeoutGetResponse; writtenCount, err = rw.Write(request) {
 		// COMPORT PENDING
 		pause(ctx.Done(), cfg.TimeoutEndResponse)
@@ -159,8 +300,25 @@ func SetEnableLog(enable bool) {
 	}
 }
 
+// SetNotify задаёт функцию уведомления о приёмопередаче всех экземпляров T.
+// Уведомления доставляются в порядке приёмопередачи через очередь размера DefaultNotifyQueueSize
+// без потерь: при переполнении очереди транзакция ожидает доставки уведомлений
 func SetNotify(f NotifyFunc) {
-	atomicNotify.Store(f)
+	if f == nil {
+		SetNotifyDispatcher(nil)
+		return
+	}
+	SetNotifyDispatcher(NewDispatcher(f, DefaultNotifyQueueSize, DefaultOverflowPolicy))
+}
+
+// SetNotifyDispatcher задаёт очередь уведомлений о приёмопередаче всех экземпляров T
+func SetNotifyDispatcher(d *Dispatcher) {
+	atomicNotify.Store(d)
+}
+
+// SetRecorder задаёт запись приёмопередачи всех экземпляров T
+func SetRecorder(r *Recorder) {
+	atomicRecorder.Store(r)
 }
 
 type result struct {
@@ -168,66 +326,92 @@ type result struct {
 	err      error
 }
 
-func (x T) getResponse(log Logger, ctx context.Context, request []byte) ([]byte, error) {
-	if ctx == nil {
-		ctx = context.Background()
+// getResponse возвращает ответ, количество выполненных попыток и ошибку
+func (x T) getResponse(log Logger, ctx context.Context, request []byte) ([]byte, int, error) {
+	retryPolicy := x.retryPolicy
+	if retryPolicy == nil {
+		retryPolicy = defaultRetryPolicy{x.cfg.TimeoutEndResponse}
 	}
 	var (
 		lastResult result
+		history    []Attempt
 	)
-	for attempt := 0; attempt < x.cfg.MaxAttemptsRead; attempt++ {
+	rr, err := newResponseReader(x.rw)
+	if err != nil {
+		return nil, 0, err
+	}
+	attempt := 0
+	for ; attempt < x.cfg.MaxAttemptsRead; attempt++ {
+		if err := rr.discard(); err != nil {
+			return nil, attempt, err
+		}
 		if err := x.write(ctx, request); err != nil {
-			return nil, err
+			if ctxErr(ctx) != nil && lastResult.err != nil {
+				return lastResult.response, attempt, merry.Appendf(err, "последняя попытка: %v", lastResult.err)
+			}
+			return nil, attempt, err
 		}
-		ctx, _ := context.WithTimeout(ctx, x.cfg.TimeoutGetResponse)
-		c := make(chan result)
 		startWaitResponseMoment := time.Now()
-		go x.waitForResponse(ctx, c)
-
-		log := internal.LogPrependSuffixKeys(log, LogKeyAttempt, attempt)
-
-		select {
-
-		case r := <-c:
-			if r.err == nil && x.prs != nil {
-				r.err = x.prs(request, r.response)
-			}
-			log = internal.LogPrependSuffixKeys(log, LogKeyDuration, time.Since(startWaitResponseMoment))
-			logAnswer(log, request, r)
-			notify(startWaitResponseMoment, request, r, x.rw, attempt)
-
-			if merry.Is(r.err, Err) {
-				lastResult = r
-				pause(ctx.Done(), x.cfg.TimeoutEndResponse)
-				continue
-			}
-			if r.err != nil {
-				return r.response, r.err
-			}
-
-			return r.response, nil
-
-		case <-ctx.Done():
-			r := result{
-				response: nil,
-				err:      ctx.Err(),
-			}
-
-			logAnswer(log, request, r)
-			notify(startWaitResponseMoment, request, r, x.rw, attempt)
+		deadline := x.responseDeadline(ctx, startWaitResponseMoment)
+		var r result
+		if received, err := x.readEcho(ctx, rr, request, deadline); err != nil {
+			r = result{nil, err}
+		} else {
+			r = x.waitForResponse(ctx, rr, request, received, deadline)
+		}
+		if r.err == nil && x.prs != nil {
+			r.err = x.prs(request, r.response)
+		}
 
-			switch ctx.Err() {
+		log := internal.LogPrependSuffixKeys(log,
+			LogKeyAttempt, attempt,
+			LogKeyDuration, time.Since(startWaitResponseMoment))
+		x.logAnswer(log, request, r)
+		x.doNotify(startWaitResponseMoment, request, r, attempt)
+		x.recordAttemptStats(request, r, time.Since(startWaitResponseMoment))
 
-			case context.DeadlineExceeded:
-				lastResult = r
-				continue
+		if r.err == nil {
+			return r.response, attempt + 1, nil
+		}
+		if ctxErr(ctx) != nil {
+			return r.response, attempt + 1, r.err
+		}
+		history = append(history, Attempt{
+			Number:   attempt,
+			Response: r.response,
+			Err:      r.err,
+			Duration: time.Since(startWaitResponseMoment),
+		})
+		retry, delay := retryPolicy.Retry(request, history)
+		if !retry {
+			return r.response, attempt + 1, r.err
+		}
+		lastResult = r
+		if delay > 0 && attempt+1 < x.cfg.MaxAttemptsRead {
+			pause(ctx.Done(), delay)
+		}
+	}
+	return lastResult.response, attempt, lastResult.err
+}
 
-			default:
-				return nil, ctx.Err()
-			}
+func (x T) send(log Logger, ctx context.Context, request []byte) error {
+	t := time.Now()
+	r := result{err: x.write(ctx, request)}
+	if r.err == nil && x.cfg.Echo {
+		rr, err := newResponseReader(x.rw)
+		if err == nil {
+			_, err = x.readEcho(ctx, rr, request, x.responseDeadline(ctx, time.Now()))
 		}
+		r.err = err
+	}
+	if r.err == nil && x.cfg.TurnaroundDelay > 0 {
+		pause(ctx.Done(), x.cfg.TurnaroundDelay)
 	}
-	return lastResult.response, lastResult.err
+	log = internal.LogPrependSuffixKeys(log, LogKeyDuration, time.Since(t))
+	x.logAnswer(log, request, r)
+	x.doNotify(t, request, r, 0)
+	x.recordAttemptStats(request, r, time.Since(t))
+	return r.err
 }
 
 func (x T) write(ctx context.Context, request []byte) error {
@@ -235,61 +419,127 @@ func (x T) write(ctx context.Context, request []byte) error {
 	if x.cfg.Pause > 0 {
 		pause(ctx.Done(), x.cfg.Pause)
 	}
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
 	return Write(ctx, request, x.rw, x.cfg)
 }
 
-func (x T) waitForResponse(ctx context.Context, c chan result) {
-
-	var response []byte
-	ctxReady := context.Background()
+// ctxErr возвращает ошибку контекста, в том числе context.DeadlineExceeded,
+// если его срок истёк, но контекст ещё не был отменён
+func ctxErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
 
-	for {
-		select {
+// responseDeadline возвращает момент истечения таймаута ожидания ответа с учётом срока контекста
+func (x T) responseDeadline(ctx context.Context, startWaitResponseMoment time.Time) time.Time {
+	deadline := startWaitResponseMoment.Add(x.cfg.TimeoutGetResponse)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	return deadline
+}
 
-		case <-ctx.Done():
-			return
+// readEcho при Config.Echo считывает эхо запроса и возвращает байты, принятые после него.
+// Если до момента deadline не было принято ни одного байта, возвращает ошибку, для которой
+// merry.Is(err, context.DeadlineExceeded) == true. Если эхо не совпадает с запросом либо не принято полностью до момента deadline, возвращает ErrEcho
+func (x T) readEcho(ctx context.Context, rr responseReader, request []byte, deadline time.Time) ([]byte, error) {
+	if !x.cfg.Echo {
+		return nil, nil
+	}
+	var echo []byte
+	for len(echo) < len(request) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !time.Now().Before(deadline) && len(echo) == 0 {
+			return nil, merry.WithMessage(context.DeadlineExceeded, "нет эха запроса")
+		}
+		if !time.Now().Before(deadline) {
+			return nil, ErrEcho.Here().Appendf("принято %d байт эха из %d: % X", len(echo), len(request), echo)
+		}
+		b, err := rr.read(ctx.Done(), readPollInterval)
+		if err != nil {
+			return nil, err
+		}
+		echo = append(echo, b...)
+		n := len(echo)
+		if n > len(request) {
+			n = len(request)
+		}
+		if !bytes.Equal(echo[:n], request[:n]) {
+			return nil, ErrEcho.Here().Appendf("эхо % X", echo[:n])
+		}
+	}
+	return echo[len(request):], nil
+}
 
-		case <-ctxReady.Done():
-			c <- result{response, nil}
-			return
+// waitForResponse считывает ответ в текущей горутине, все таймеры освобождаются до возврата.
+// received - байты ответа, принятые ранее.
+// Если до момента deadline не было принято ни одного байта, возвращает context.DeadlineExceeded.
+// После начала приёма ответа deadline не учитывается: ответ считается принятым
+// по истечении TimeoutEndResponse с момента приёма последнего байта либо по frameComplete
+func (x T) waitForResponse(ctx context.Context, rr responseReader, request, received []byte, deadline time.Time) result {
 
-		default:
-			bytesToReadCount, err := x.rw.Read(nil)
-			if err != nil {
-				c <- result{response, merry.Wrap(err)}
-				return
-			}
-			if bytesToReadCount == 0 {
-				pause(ctx.Done(), time.Millisecond)
-				continue
-			}
-			b, err := x.read(bytesToReadCount)
-			if err != nil {
-				c <- result{response, merry.Wrap(err)}
-				return
+	var (
+		response          []byte
+		lastReceiveMoment time.Time
+	)
+	if len(received) > 0 {
+		response = received
+		lastReceiveMoment = time.Now()
+		if x.frameComplete != nil {
+			complete, err := x.frameComplete(request, response)
+			if err != nil || complete {
+				return result{response, err}
 			}
-			response = append(response, b...)
-			ctx = context.Background()
-			ctxReady, _ = context.WithTimeout(context.Background(), x.cfg.TimeoutEndResponse)
 		}
 	}
-}
 
-func (x T) read(bytesToReadCount int) ([]byte, error) {
+	for {
+		if len(response) > 0 && time.Since(lastReceiveMoment) >= x.cfg.TimeoutEndResponse {
+			return result{response, nil}
+		}
+		if err := ctx.Err(); err != nil {
+			return result{response, err}
+		}
+		if len(response) == 0 && !time.Now().Before(deadline) {
+			return result{nil, context.DeadlineExceeded}
+		}
 
-	b := make([]byte, bytesToReadCount)
-	readCount, err := x.rw.Read(b)
-	if err != nil {
-		return nil, merry.Wrap(err)
-	}
-	if readCount != bytesToReadCount {
-		return nil, merry.Errorf("считано %d байт из %d: % X", readCount, bytesToReadCount, b[:readCount])
+		b, err := rr.read(ctx.Done(), readPollInterval)
+		if merry.Is(err, io.EOF) && len(response) > 0 {
+			// поток данных порта завершён
+			return result{response, nil}
+		}
+		if err != nil {
+			return result{response, err}
+		}
+		if len(b) == 0 {
+			continue
+		}
+		response = append(response, b...)
+		lastReceiveMoment = time.Now()
+		if x.frameComplete != nil {
+			complete, err := x.frameComplete(request, response)
+			if err != nil {
+				return result{response, err}
+			}
+			if complete {
+				return result{response, nil}
+			}
+		}
 	}
-	return b, nil
 }
 
-func logAnswer(log Logger, request []byte, r result) {
-	if log == nil || !isLogEnabled() {
+func (x T) logAnswer(log Logger, request []byte, r result) {
+	if log == nil || !x.isLogEnabled() {
 		return
 	}
 	str := fmt.Sprintf("% X --> % X", request, r.response)
@@ -313,36 +563,61 @@ func logAnswer(log Logger, request []byte, r result) {
 	log.PrintErr(str)
 }
 
-func isLogEnabled() bool {
+func (x T) isLogEnabled() bool {
+	if x.logEnabled != nil {
+		return *x.logEnabled
+	}
 	return atomic.LoadInt32(&atomicEnableLog) != 0
 }
 
-func notify(startWaitResponseMoment time.Time, req []byte, r result, rw io.ReadWriter, attempt int) {
-	ntf := getNotifyFunc()
-	if ntf == nil {
+func (x T) doNotify(startWaitResponseMoment time.Time, req []byte, r result, attempt int) {
+	ntf := getNotifyDispatcher()
+	rec := getRecorder()
+	if ntf == nil && x.notify == nil && rec == nil && x.recorder == nil {
 		return
 	}
 	i := Info{
+		Time:     startWaitResponseMoment,
 		Request:  make([]byte, len(req)),
 		Response: make([]byte, len(r.response)),
 		Err:      r.err,
 		Duration: time.Since(startWaitResponseMoment),
 		Attempt:  attempt,
+		LockWait: x.lockWait,
 	}
-	if s, f := rw.(fmt.Stringer); f {
+	if s, f := x.rw.(fmt.Stringer); f {
 		i.Port = s.String()
 	}
 	copy(i.Request, req)
 	copy(i.Response, r.response)
-	go ntf(i)
+	if ntf != nil {
+		ntf.Notify(i)
+	}
+	if x.notify != nil {
+		x.notify.Notify(i)
+	}
+	if rec != nil {
+		rec.Record(i)
+	}
+	if x.recorder != nil && x.recorder != rec {
+		x.recorder.Record(i)
+	}
 }
 
-func getNotifyFunc() NotifyFunc {
+func getNotifyDispatcher() *Dispatcher {
 	x := atomicNotify.Load()
 	if x == nil {
 		return nil
 	}
-	return x.(NotifyFunc)
+	return x.(*Dispatcher)
+}
+
+func getRecorder() *Recorder {
+	x := atomicRecorder.Load()
+	if x == nil {
+		return nil
+	}
+	return x.(*Recorder)
 }
 
 func pause(chDone <-chan struct{}, d time.Duration) {
@@ -358,35 +633,48 @@ func pause(chDone <-chan struct{}, d time.Duration) {
 	}
 }
 
-func (x T) lockPort() {
+// lockPort захватывает порт x.port, ожидая его освобождения не дольше, чем позволяет ctx.
+// Ожидающие транзакции получают порт в порядке приоритета. Возвращает время ожидания
+func (x T) lockPort(ctx context.Context) (time.Duration, error) {
 	if len(x.port) == 0 {
-		return
+		return 0, nil
 	}
-	o, _ := lockPorts.LoadOrStore(x.port, new(sync.Mutex))
-	mu, ok := o.(*sync.Mutex)
+	priority, ok := PriorityFromContext(ctx)
 	if !ok {
-		panic("unexpected")
+		priority = x.priority
 	}
-	mu.Lock()
+	o, _ := lockPorts.LoadOrStore(x.port, new(scheduler))
+	wait, err := o.(*scheduler).acquire(ctx, priority)
+	if err != nil {
+		return wait, merry.Prependf(err, "ожидание освобождения порта %s", x.port)
+	}
+	return wait, nil
 }
 
-func (x T) unlockPort() {
+// lockPortIdle захватывает порт x.port для Listener, когда порт не ожидает ни одна транзакция.
+// Возвращаемый контекст отменяется, когда порт требуется транзакции
+func (x T) lockPortIdle(ctx context.Context) (context.Context, error) {
+	o, _ := lockPorts.LoadOrStore(x.port, new(scheduler))
+	return o.(*scheduler).acquireIdle(ctx)
+}
+
+func (x T) unlockPort() error {
 	if len(x.port) == 0 {
-		return
+		return nil
 	}
 	o, ok := lockPorts.Load(x.port)
 	if !ok {
-		panic("unlock not locked port: " + x.port)
+		return merry.Errorf("освобождение не захваченного порта %s", x.port)
 	}
-	mu, ok := o.(*sync.Mutex)
-	if !ok {
-		panic("unexpected")
+	if err := o.(*scheduler).release(); err != nil {
+		return merry.Prepend(err, x.port)
 	}
-	mu.Unlock()
+	return nil
 }
 
 var (
 	atomicEnableLog int32 = 1
 	atomicNotify          = new(atomic.Value)
+	atomicRecorder        = new(atomic.Value)
 	lockPorts       sync.Map
 )
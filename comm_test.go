@@ -0,0 +1,310 @@
+package comm_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"github.com/powerman/structlog"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifyIsolation(t *testing.T) {
+	var (
+		global = make(chan comm.Info, 10)
+		c1     = make(chan comm.Info, 10)
+		c2     = make(chan comm.Info, 10)
+	)
+	comm.SetNotify(func(i comm.Info) {
+		global <- i
+	})
+	defer comm.SetNotify(nil)
+
+	cm1 := newEchoMock().WithNotify(func(i comm.Info) {
+		c1 <- i
+	})
+	cm2 := newEchoMock().WithNotify(func(i comm.Info) {
+		c2 <- i
+	})
+
+	if _, err := cm1.GetResponse(nil, context.Background(), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm2.GetResponse(nil, context.Background(), []byte{2}); err != nil {
+		t.Fatal(err)
+	}
+
+	assertInfoRequests(t, c1, []byte{1})
+	assertInfoRequests(t, c2, []byte{2})
+	assertInfoRequests(t, global, []byte{1}, []byte{2})
+}
+
+func TestLogEnabledIsolation(t *testing.T) {
+	comm.SetEnableLog(false)
+	defer comm.SetEnableLog(true)
+
+	var buf bytes.Buffer
+	log := structlog.New().SetOutput(&buf)
+
+	if _, err := newEchoMock().WithLogEnabled(true).GetResponse(log, context.Background(), []byte{0xA1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newEchoMock().GetResponse(log, context.Background(), []byte{0xB2}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "A1") {
+		t.Errorf("log of enabled instance expected: %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "B2") {
+		t.Errorf("log of disabled instance not expected: %q", buf.String())
+	}
+
+	comm.SetEnableLog(true)
+	buf.Reset()
+	if _, err := newEchoMock().WithLogEnabled(false).GetResponse(log, context.Background(), []byte{0xC3}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("log of disabled instance not expected: %q", buf.String())
+	}
+}
+
+func newEchoMock() comm.T {
+	return comport.NewMock(func(req []byte) []byte {
+		return req
+	})
+}
+
+func assertInfoRequests(t *testing.T, c chan comm.Info, requests ...[]byte) {
+	t.Helper()
+	var got [][]byte
+	for range requests {
+		select {
+		case i := <-c:
+			got = append(got, i.Request)
+		case <-time.After(time.Second):
+			t.Fatalf("no notification, got % X, expected % X", got, requests)
+		}
+	}
+	select {
+	case i := <-c:
+		t.Fatalf("unexpected notification % X", i.Request)
+	case <-time.After(50 * time.Millisecond):
+	}
+	for _, r := range requests {
+		found := false
+		for _, g := range got {
+			found = found || bytes.Equal(r, g)
+		}
+		if !found {
+			t.Errorf("notification % X expected, got % X", r, got)
+		}
+	}
+}
+
+func TestNoGoroutineLeak(t *testing.T) {
+	const count = 1000
+
+	before := runtime.NumGoroutine()
+
+	cm := comm.New(silentPort{}, comm.Config{
+		TimeoutGetResponse: 100 * time.Microsecond,
+		TimeoutEndResponse: 100 * time.Microsecond,
+		MaxAttemptsRead:    2,
+	})
+	for i := 0; i < count; i++ {
+		_, err := cm.GetResponse(nil, context.Background(), []byte{1})
+		if !merry.Is(err, context.DeadlineExceeded) || !merry.Is(err, comm.Err) {
+			t.Fatalf("timeout expected: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < count; i++ {
+		_, err := cm.GetResponse(nil, ctx, []byte{1})
+		if !merry.Is(err, context.Canceled) {
+			t.Fatalf("cancel expected: %v", err)
+		}
+	}
+
+	cm = newEchoMock()
+	for i := 0; i < count; i++ {
+		if _, err := cm.GetResponse(nil, context.Background(), []byte{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("goroutines before %d, after %d", before, after)
+	}
+}
+
+// silentPort никогда не отвечает на запросы
+type silentPort struct{}
+
+func (silentPort) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (silentPort) Read([]byte) (int, error) {
+	return 0, nil
+}
+
+func (silentPort) Available() (int, error) {
+	return 0, nil
+}
+
+func TestTotalTimeout(t *testing.T) {
+	var requestsCount int32
+	port := &countingPort{n: &requestsCount}
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: 50 * time.Millisecond,
+		MaxAttemptsRead:    10,
+		TotalTimeout:       120 * time.Millisecond,
+	})
+	t0 := time.Now()
+	_, err := cm.GetResponse(nil, context.Background(), []byte{1})
+	if d := time.Since(t0); d > 200*time.Millisecond {
+		t.Errorf("duration %v exceeds total timeout", d)
+	}
+	if !merry.Is(err, context.DeadlineExceeded) || !merry.Is(err, comm.Err) {
+		t.Fatalf("timeout expected: %v", err)
+	}
+	if n := atomic.LoadInt32(&requestsCount); n != 3 {
+		t.Errorf("%d requests, expected 3", n)
+	}
+	if s := err.Error(); strings.Count(s, "выполнено попыток") != 1 || strings.Contains(s, "повторов") || !strings.Contains(s, "выполнено попыток 3 из 10") {
+		t.Errorf("attempts count must be reported once: %v", err)
+	}
+
+	// таймаут контекста учитывается без TotalTimeout
+	atomic.StoreInt32(&requestsCount, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 70*time.Millisecond)
+	defer cancel()
+	t0 = time.Now()
+	_, err = cm.WithConfig(comm.Config{
+		TimeoutGetResponse: 50 * time.Millisecond,
+		MaxAttemptsRead:    10,
+	}).GetResponse(nil, ctx, []byte{1})
+	if d := time.Since(t0); d > 150*time.Millisecond {
+		t.Errorf("duration %v exceeds context timeout", d)
+	}
+	if !merry.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("timeout expected: %v", err)
+	}
+	if n := atomic.LoadInt32(&requestsCount); n != 2 {
+		t.Errorf("%d requests, expected 2", n)
+	}
+}
+
+// countingPort считает запросы и никогда не отвечает на них
+type countingPort struct {
+	silentPort
+	n *int32
+}
+
+func (x *countingPort) Write(p []byte) (int, error) {
+	atomic.AddInt32(x.n, 1)
+	return len(p), nil
+}
+
+func TestNotifyLockWait(t *testing.T) {
+	c := make(chan comm.Info, 10)
+	cm := comm.New(&delayPort{delay: 50 * time.Millisecond}, comm.Config{
+		TimeoutGetResponse: time.Second,
+	}).WithLockPort("TestNotifyLockWait").WithNotify(func(i comm.Info) {
+		c <- i
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := cm.GetResponse(nil, context.Background(), []byte{1}); err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := cm.GetResponse(nil, context.Background(), []byte{2}); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	for i := 0; i < 2; i++ {
+		info := <-c
+		if info.Request[0] == 2 && info.LockWait < 20*time.Millisecond {
+			t.Errorf("lock wait %v", info.LockWait)
+		}
+	}
+}
+
+// delayPort отвечает копией запроса через delay после его записи
+type delayPort struct {
+	delay time.Duration
+	mu    sync.Mutex
+	t     time.Time
+	req   []byte
+}
+
+func (x *delayPort) Write(p []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.t = time.Now()
+	x.req = append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func (x *delayPort) Available() (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if time.Since(x.t) < x.delay {
+		return 0, nil
+	}
+	return len(x.req), nil
+}
+
+func (x *delayPort) Read(p []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n := copy(p, x.req)
+	x.req = x.req[n:]
+	return n, nil
+}
+
+func TestSend(t *testing.T) {
+	var requestsCount int32
+	c := make(chan comm.Info, 10)
+	cm := comm.New(&countingPort{n: &requestsCount}, comm.Config{
+		TimeoutGetResponse: time.Second,
+		MaxAttemptsRead:    3,
+		Pause:              10 * time.Millisecond,
+		TurnaroundDelay:    30 * time.Millisecond,
+	}).WithNotify(func(i comm.Info) {
+		c <- i
+	})
+	t0 := time.Now()
+	if err := cm.Send(nil, context.Background(), []byte{0, 6}); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(t0); d < 40*time.Millisecond || d > 500*time.Millisecond {
+		t.Errorf("pause and turnaround delay expected: %v", d)
+	}
+	if n := atomic.LoadInt32(&requestsCount); n != 1 {
+		t.Errorf("%d requests, expected 1", n)
+	}
+	select {
+	case i := <-c:
+		if !bytes.Equal(i.Request, []byte{0, 6}) || len(i.Response) != 0 || i.Err != nil {
+			t.Errorf("unexpected notification %+v", i)
+		}
+		if i.Duration < 30*time.Millisecond {
+			t.Errorf("turnaround delay is not included in duration: %v", i.Duration)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no notification")
+	}
+}
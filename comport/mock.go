@@ -32,12 +32,22 @@ func (x *mockComport) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+func (x *mockComport) Available() (int, error) {
+	if x.req != nil && len(x.resp) == 0 {
+		return 0, merry.Errorf("unsupported request %02X", x.req)
+	}
+	return len(x.resp), nil
+}
+
 func (x *mockComport) Read(p []byte) (int, error) {
 	if len(x.resp) == 0 {
 		return 0, merry.Errorf("unsupported request %02X", x.req)
 	}
-	if len(p) < len(x.resp) {
-		return len(x.resp), nil
+	n := copy(p, x.resp)
+	x.resp = x.resp[n:]
+	if len(x.resp) == 0 {
+		// ответ считан полностью
+		x.req = nil
 	}
-	return copy(p, x.resp), nil
+	return n, nil
 }
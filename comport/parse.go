@@ -0,0 +1,270 @@
+package comport
+
+import (
+	"fmt"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLScheme - схема URL параметров порта, например serial:///dev/ttyUSB0?baud=9600&parity=E
+const URLScheme = "serial"
+
+// String возвращает параметры порта в виде COM3:9600,8N1, см. ParseConfig
+func (c Config) String() string {
+	return fmt.Sprintf("%s:%d,%s", c.Name, c.Baud, c.frame())
+}
+
+// ParseConfig разбирает параметры порта, заданные в одной из форм:
+//
+//	COM3:9600,8N1
+//	/dev/ttyUSB0@19200/8E1.5
+//	serial:///dev/ttyUSB0?baud=9600&parity=E
+//
+// Формат символа - число бит данных, чётность N, O, E, M или S и число стоп-битов 1, 1.5 или 2.
+// Если формат символа либо скорость не заданы, используются значения DefaultConfig
+func ParseConfig(s string) (Config, error) {
+	if strings.HasPrefix(s, URLScheme+":") || strings.Contains(s, "://") {
+		x, err := ParseSettingsURL(s)
+		return x.Port, err
+	}
+	c, err := parseConfig(s)
+	if err != nil {
+		return c, merry.Prependf(err, "параметры порта %q", s)
+	}
+	if err := c.Validate(); err != nil {
+		return c, merry.Prependf(err, "параметры порта %q", s)
+	}
+	return c, nil
+}
+
+// URL возвращает параметры порта и приёмопередачи в виде URL, см. ParseSettingsURL.
+// Параметры приёмопередачи, совпадающие с comm.DefaultConfig, не включаются
+func (x PortSettings) URL() string {
+	q := url.Values{}
+	q.Set("baud", strconv.Itoa(x.Port.Baud))
+	q.Set("size", strconv.Itoa(int(x.Port.dataBits())))
+	q.Set("parity", string(x.Port.parityOrDefault()))
+	q.Set("stop_bits", x.Port.stopBitsOrDefault().String())
+	if x.Port.ReadTimeout != DefaultConfig("").ReadTimeout {
+		q.Set("read_timeout", x.Port.ReadTimeout.String())
+	}
+	def := comm.DefaultConfig()
+	for _, f := range commURLFields {
+		if v := f.get(x.Comm); v != f.get(def) {
+			q.Set(f.name, v)
+		}
+	}
+	u := url.URL{
+		Scheme:   URLScheme,
+		Path:     x.Port.Name,
+		RawQuery: q.Encode(),
+	}
+	if !strings.HasPrefix(x.Port.Name, "/") {
+		// имя порта Windows, например COM3
+		u = url.URL{Scheme: URLScheme, Opaque: x.Port.Name, RawQuery: u.RawQuery}
+	}
+	return u.String()
+}
+
+// ParseSettingsURL разбирает параметры порта и приёмопередачи, заданные URL вида
+//
+//	serial:///dev/ttyUSB0?baud=9600&size=8&parity=E&stop_bits=1&timeout_get_response=500ms
+//	serial:COM3?baud=9600
+//
+// Имена параметров совпадают с именами полей Config и comm.Config в JSON.
+// Значения незаданных параметров берутся из DefaultConfig и comm.DefaultConfig
+func ParseSettingsURL(s string) (PortSettings, error) {
+	x := PortSettings{
+		Port: DefaultConfig(""),
+		Comm: comm.DefaultConfig(),
+	}
+	wrapErr := func(err error) error {
+		return merry.Prependf(err, "параметры порта %q", s)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return x, wrapErr(err)
+	}
+	if u.Scheme != URLScheme {
+		return x, wrapErr(merry.Errorf("ожидалась схема %s: %s", URLScheme, u.Scheme))
+	}
+	x.Port.Name = u.Host + u.Path
+	if len(u.Opaque) > 0 {
+		x.Port.Name = u.Opaque
+	}
+	fields := make(map[string]func(string) error)
+	for _, f := range commURLFields {
+		f := f
+		fields[f.name] = func(s string) error {
+			return f.set(&x.Comm, s)
+		}
+	}
+	fields["baud"] = func(s string) (err error) {
+		x.Port.Baud, err = strconv.Atoi(s)
+		return
+	}
+	fields["size"] = func(s string) error {
+		n, err := strconv.ParseUint(s, 10, 8)
+		x.Port.Size = byte(n)
+		return err
+	}
+	fields["parity"] = func(s string) error {
+		return x.Port.Parity.UnmarshalText([]byte(s))
+	}
+	fields["stop_bits"] = func(s string) error {
+		return x.Port.StopBits.UnmarshalText([]byte(s))
+	}
+	fields["read_timeout"] = func(s string) (err error) {
+		x.Port.ReadTimeout, err = time.ParseDuration(s)
+		return
+	}
+
+	q := u.Query()
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		set, ok := fields[k]
+		if !ok {
+			return x, wrapErr(merry.Errorf("неизвестный параметр %s", k))
+		}
+		if err := set(q.Get(k)); err != nil {
+			return x, wrapErr(merry.Prepend(err, k))
+		}
+	}
+	if err := x.Validate(); err != nil {
+		return x, wrapErr(err)
+	}
+	return x, nil
+}
+
+func parseConfig(s string) (Config, error) {
+	var name, baud, frame string
+	if n := strings.LastIndex(s, "@"); n >= 0 {
+		name = s[:n]
+		baud = s[n+1:]
+		if n := strings.Index(baud, "/"); n >= 0 {
+			baud, frame = baud[:n], baud[n+1:]
+		}
+	} else if n := strings.LastIndex(s, ":"); n >= 0 && isColonSettings(s[n+1:]) {
+		name = s[:n]
+		baud = s[n+1:]
+		if n := strings.Index(baud, ","); n >= 0 {
+			baud, frame = baud[:n], baud[n+1:]
+		}
+	} else {
+		name = s
+	}
+	c := DefaultConfig(strings.TrimSpace(name))
+	if baud = strings.TrimSpace(baud); len(baud) > 0 {
+		var err error
+		if c.Baud, err = strconv.Atoi(baud); err != nil {
+			return c, merry.Errorf("скорость %q", baud)
+		}
+	}
+	if frame = strings.TrimSpace(frame); len(frame) > 0 {
+		if err := c.parseFrame(frame); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// isColonSettings возвращает true, если s после последнего двоеточия имеет вид скорость[,формат символа].
+// Иначе двоеточие является частью имени порта, например /dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0
+func isColonSettings(s string) bool {
+	if n := strings.Index(s, ","); n >= 0 {
+		s = s[:n]
+	}
+	_, err := strconv.Atoi(strings.TrimSpace(s))
+	return err == nil
+}
+
+// parseFrame разбирает формат символа, например 8N1 или 8E1.5
+func (c *Config) parseFrame(s string) error {
+	if len(s) < 3 || s[0] < '5' || s[0] > '8' {
+		return merry.Errorf("формат символа %q", s)
+	}
+	c.Size = s[0] - '0'
+	if err := c.Parity.UnmarshalText([]byte(s[1:2])); err != nil {
+		return merry.Prependf(err, "формат символа %q", s)
+	}
+	if err := c.StopBits.UnmarshalText([]byte(s[2:])); err != nil || s[2:] == "15" {
+		return merry.Errorf("формат символа %q: стоп-биты %q", s, s[2:])
+	}
+	return nil
+}
+
+// frame возвращает формат символа, например 8N1
+func (c Config) frame() string {
+	return fmt.Sprintf("%d%c%s", c.dataBits(), c.parityOrDefault(), c.stopBitsOrDefault())
+}
+
+func (c Config) parityOrDefault() Parity {
+	if c.Parity == 0 {
+		return ParityNone
+	}
+	return c.Parity
+}
+
+func (c Config) stopBitsOrDefault() StopBits {
+	if c.StopBits == 0 {
+		return Stop1
+	}
+	return c.StopBits
+}
+
+// commURLField - параметр URL, соответствующий полю comm.Config
+type commURLField struct {
+	name string
+	get  func(comm.Config) string
+	set  func(*comm.Config, string) error
+}
+
+func durationURLField(name string, p func(*comm.Config) *time.Duration) commURLField {
+	return commURLField{
+		name: name,
+		get: func(c comm.Config) string {
+			return p(&c).String()
+		},
+		set: func(c *comm.Config, s string) (err error) {
+			*p(c), err = time.ParseDuration(s)
+			return
+		},
+	}
+}
+
+var commURLFields = []commURLField{
+	durationURLField("timeout_get_response", func(c *comm.Config) *time.Duration { return &c.TimeoutGetResponse }),
+	durationURLField("timeout_end_response", func(c *comm.Config) *time.Duration { return &c.TimeoutEndResponse }),
+	durationURLField("pause", func(c *comm.Config) *time.Duration { return &c.Pause }),
+	durationURLField("total_timeout", func(c *comm.Config) *time.Duration { return &c.TotalTimeout }),
+	durationURLField("turnaround_delay", func(c *comm.Config) *time.Duration { return &c.TurnaroundDelay }),
+	{
+		name: "max_attempts_read",
+		get: func(c comm.Config) string {
+			return strconv.Itoa(c.MaxAttemptsRead)
+		},
+		set: func(c *comm.Config, s string) (err error) {
+			c.MaxAttemptsRead, err = strconv.Atoi(s)
+			return
+		},
+	},
+	{
+		name: "echo",
+		get: func(c comm.Config) string {
+			return strconv.FormatBool(c.Echo)
+		},
+		set: func(c *comm.Config, s string) (err error) {
+			c.Echo, err = strconv.ParseBool(s)
+			return
+		},
+	},
+}
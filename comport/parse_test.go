@@ -0,0 +1,93 @@
+package comport
+
+import (
+	"github.com/fpawel/comm"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, x := range []struct {
+		s    string
+		want Config
+		str  string
+	}{
+		{"COM3:9600,8N1", config("COM3", 9600, 8, ParityNone, Stop1), "COM3:9600,8N1"},
+		{"/dev/ttyUSB0@19200/8E1", config("/dev/ttyUSB0", 19200, 8, ParityEven, Stop1), "/dev/ttyUSB0:19200,8E1"},
+		{"COM10:2400,7O1.5", config("COM10", 2400, 7, ParityOdd, Stop1Half), "COM10:2400,7O1.5"},
+		{"/dev/ttyS0@115200/8N2", config("/dev/ttyS0", 115200, 8, ParityNone, Stop2), "/dev/ttyS0:115200,8N2"},
+		{"COM1:19200", config("COM1", 19200, 8, ParityNone, Stop1), "COM1:19200,8N1"},
+		{"COM1", config("COM1", 9600, 8, ParityNone, Stop1), "COM1:9600,8N1"},
+		{
+			"/dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0",
+			config("/dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0", 9600, 8, ParityNone, Stop1),
+			"/dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0:9600,8N1",
+		},
+		{
+			"/dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0:19200,8E1",
+			config("/dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0", 19200, 8, ParityEven, Stop1),
+			"/dev/serial/by-path/pci-0000:00:14.0-usb-0:1:1.0-port0:19200,8E1",
+		},
+		{"serial:///dev/ttyUSB0?baud=9600&parity=E", config("/dev/ttyUSB0", 9600, 8, ParityEven, Stop1), "/dev/ttyUSB0:9600,8E1"},
+		{"serial:COM3?baud=4800&stop_bits=2", config("COM3", 4800, 8, ParityNone, Stop2), "COM3:4800,8N2"},
+	} {
+		c, err := ParseConfig(x.s)
+		if err != nil {
+			t.Errorf("%s: %v", x.s, err)
+			continue
+		}
+		if c != x.want {
+			t.Errorf("%s: got %#v, want %#v", x.s, c, x.want)
+		}
+		if c.String() != x.str {
+			t.Errorf("%s: got %s, want %s", x.s, c.String(), x.str)
+		}
+		if c2, err := ParseConfig(c.String()); err != nil || c2 != c {
+			t.Errorf("%s: round trip %#v: %v", x.s, c2, err)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"COM3@fast/8N1",
+		"COM3:9600,9N1",
+		"COM3:9600,8X1",
+		"COM3:9600,8N3",
+		"COM3:9600,8N15",
+		"serial:COM3?baud=9600&speed=1",
+		"http://COM3",
+	} {
+		if _, err := ParseConfig(s); err == nil {
+			t.Errorf("%q: error expected", s)
+		}
+	}
+}
+
+func TestSettingsURL(t *testing.T) {
+	x := PortSettings{
+		Port: config("/dev/ttyUSB0", 19200, 8, ParityEven, Stop1Half),
+		Comm: comm.DefaultConfig(),
+	}
+	x.Comm.TimeoutGetResponse = 500 * time.Millisecond
+	x.Comm.Echo = true
+
+	const want = "serial:///dev/ttyUSB0?baud=19200&echo=true&parity=E&size=8&stop_bits=1.5&timeout_get_response=500ms"
+	if s := x.URL(); s != want {
+		t.Errorf("got %s, want %s", s, want)
+	}
+	for _, x := range []PortSettings{x, {Port: DefaultConfig("COM3"), Comm: comm.DefaultConfig()}} {
+		got, err := ParseSettingsURL(x.URL())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != x {
+			t.Errorf("%s: got %+v, want %+v", x.URL(), got, x)
+		}
+	}
+}
+
+func config(name string, baud int, size byte, parity Parity, stopBits StopBits) Config {
+	c := DefaultConfig(name)
+	c.Baud, c.Size, c.Parity, c.StopBits = baud, size, parity, stopBits
+	return c
+}
@@ -74,6 +74,19 @@ func (x *Port) Read(buf []byte) (int, error) {
 	return n, err
 }
 
+// Available возвращает количество принятых байт, доступных для чтения без блокировки.
+// Реализует comm.AvailableReader
+func (x *Port) Available() (int, error) {
+	if err := x.open(); err != nil {
+		return 0, err
+	}
+	n, err := x.p.BytesToReadCount()
+	if err != nil {
+		err = merry.Prependf(err, "%s: считывание", x)
+	}
+	return n, err
+}
+
 func (x *Port) String() string {
 	if len(x.c.Name) > 0 {
 		return x.c.Name
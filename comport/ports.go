@@ -2,50 +2,87 @@ package comport
 
 import (
 	"errors"
+	"fmt"
 	"github.com/ansel1/merry"
-	"golang.org/x/sys/windows/registry"
+	"strings"
 )
 
-func Ports() ([]string, error) {
+// PortInfo содержит сведения о СОМ порте, представленном в системе
+type PortInfo struct {
+	Name         string `json:"name" yaml:"name"`                   // имя порта: COM3, /dev/ttyUSB0
+	ByID         string `json:"by_id" yaml:"by_id"`                 // постоянное имя порта, не зависящее от порядка подключения
+	VID          uint16 `json:"vid" yaml:"vid"`                     // USB vendor ID, 0 если порт не USB
+	PID          uint16 `json:"pid" yaml:"pid"`                     // USB product ID, 0 если порт не USB
+	SerialNumber string `json:"serial_number" yaml:"serial_number"` // серийный номер USB устройства
+	Manufacturer string `json:"manufacturer" yaml:"manufacturer"`   // производитель USB устройства
+	Product      string `json:"product" yaml:"product"`             // наименование USB устройства
+	Driver       string `json:"driver" yaml:"driver"`               // драйвер порта
+}
 
-	root, err := registry.OpenKey(registry.LOCAL_MACHINE, serialCommKey, registry.QUERY_VALUE)
-	if err != nil {
-		return nil, err
+// IsUSB возвращает true, если порт предоставлен USB устройством
+func (x PortInfo) IsUSB() bool {
+	return x.VID != 0 || x.PID != 0
+}
+
+func (x PortInfo) String() string {
+	var xs []string
+	if len(x.Manufacturer) > 0 {
+		xs = append(xs, x.Manufacturer)
+	}
+	if len(x.Product) > 0 {
+		xs = append(xs, x.Product)
+	}
+	if x.IsUSB() {
+		xs = append(xs, fmt.Sprintf("%04X:%04X", x.VID, x.PID))
 	}
+	if len(x.SerialNumber) > 0 {
+		xs = append(xs, "SN "+x.SerialNumber)
+	}
+	if len(x.Driver) > 0 {
+		xs = append(xs, x.Driver)
+	}
+	if len(xs) == 0 {
+		return x.Name
+	}
+	return x.Name + " (" + strings.Join(xs, ", ") + ")"
+}
 
-	ks, err := root.ReadValueNames(0)
+// Ports возвращает имена СОМ портов, представленных в системе
+func Ports() ([]string, error) {
+	ports, err := PortsInfo()
 	if err != nil {
 		return nil, err
 	}
-	var ports []string
-	for _, k := range ks {
-		port, _, err := root.GetStringValue(k)
-		if err != nil {
-			return nil, err
-		}
-		ports = append(ports, port)
+	var xs []string
+	for _, p := range ports {
+		xs = append(xs, p.Name)
 	}
-	return ports, nil
+	return xs, nil
 }
 
+// CheckPortNameIsValid проверяет, что СОМ порт с именем portName представлен в системе.
+// В качестве portName допускается как имя порта, так и его постоянное имя PortInfo.ByID
 func CheckPortNameIsValid(portName string) error {
 	if len(portName) == 0 {
 		return errors.New("не задано имя СОМ порта")
 	}
-	ports, err := Ports()
+	ports, err := PortsInfo()
 	if err != nil {
 		return merry.Errorf("не удалось получить список СОМ портов, представленных в системе: %w", err)
 	}
+	return checkPortNameIsValid(portName, ports)
+}
 
+func checkPortNameIsValid(portName string, ports []PortInfo) error {
 	if len(ports) == 0 {
 		return errors.New("СОМ порты отсутствуют")
 	}
-	for _, s := range ports {
-		if portName == s {
+	var names []string
+	for _, p := range ports {
+		if portName == p.Name || len(p.ByID) > 0 && portName == p.ByID {
 			return nil
 		}
+		names = append(names, p.Name)
 	}
-	return merry.Errorf("СОМ порт %q не доступен. Список доступных СОМ портов: %s", portName, ports)
+	return merry.Errorf("СОМ порт %q не доступен. Список доступных СОМ портов: %s", portName, names)
 }
-
-const serialCommKey = `hardware\devicemap\serialcomm`
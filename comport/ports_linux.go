@@ -0,0 +1,126 @@
+//go:build linux
+// +build linux
+
+package comport
+
+import (
+	"github.com/ansel1/merry"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortsInfo возвращает сведения о СОМ портах, представленных в системе
+func PortsInfo() ([]PortInfo, error) {
+	return PortsInfoFromSysfs("/sys", "/dev")
+}
+
+// PortsInfoFromSysfs возвращает сведения о СОМ портах из дерева sysfs с корнем sysRoot
+// и каталога устройств devRoot
+func PortsInfoFromSysfs(sysRoot, devRoot string) ([]PortInfo, error) {
+	classDir := filepath.Join(sysRoot, "class", "tty")
+	entries, err := ioutil.ReadDir(classDir)
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+	byID := readSerialByID(devRoot)
+
+	var ports []PortInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		ttyDir := filepath.Join(classDir, name)
+
+		// виртуальные терминалы не имеют устройства
+		deviceDir, err := filepath.EvalSymlinks(filepath.Join(ttyDir, "device"))
+		if err != nil {
+			continue
+		}
+		// зарезервированные драйвером 8250 порты без UART
+		if s, err := readSysfsString(filepath.Join(ttyDir, "type")); err == nil && s == "0" {
+			continue
+		}
+
+		p := PortInfo{
+			Name:   filepath.Join(devRoot, name),
+			ByID:   byID[name],
+			Driver: sysfsLinkBase(filepath.Join(deviceDir, "driver")),
+		}
+		if len(p.Driver) == 0 {
+			p.Driver = sysfsLinkBase(filepath.Join(filepath.Dir(deviceDir), "driver"))
+		}
+		if usbDir := findUSBDeviceDir(sysRoot, deviceDir); len(usbDir) > 0 {
+			p.VID = readSysfsHex16(filepath.Join(usbDir, "idVendor"))
+			p.PID = readSysfsHex16(filepath.Join(usbDir, "idProduct"))
+			p.SerialNumber, _ = readSysfsString(filepath.Join(usbDir, "serial"))
+			p.Manufacturer, _ = readSysfsString(filepath.Join(usbDir, "manufacturer"))
+			p.Product, _ = readSysfsString(filepath.Join(usbDir, "product"))
+		}
+		ports = append(ports, p)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Name < ports[j].Name
+	})
+	return ports, nil
+}
+
+// findUSBDeviceDir возвращает каталог USB устройства, которому принадлежит устройство порта deviceDir,
+// либо пустую строку, если порт не USB
+func findUSBDeviceDir(sysRoot, deviceDir string) string {
+	sysRoot, err := filepath.EvalSymlinks(sysRoot)
+	if err != nil {
+		return ""
+	}
+	for dir := deviceDir; len(dir) > len(sysRoot) && strings.HasPrefix(dir, sysRoot); dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "idVendor")); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// readSerialByID возвращает постоянные имена портов из devRoot/serial/by-id по именам портов
+func readSerialByID(devRoot string) map[string]string {
+	r := make(map[string]string)
+	dir := filepath.Join(devRoot, "serial", "by-id")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return r
+	}
+	for _, entry := range entries {
+		link := filepath.Join(dir, entry.Name())
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		r[filepath.Base(target)] = link
+	}
+	return r
+}
+
+func sysfsLinkBase(link string) string {
+	target, err := os.Readlink(link)
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+func readSysfsString(filename string) (string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readSysfsHex16(filename string) uint16 {
+	s, err := readSysfsString(filename)
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseUint(s, 16, 16)
+	return uint16(v)
+}
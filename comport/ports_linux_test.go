@@ -0,0 +1,122 @@
+//go:build linux
+// +build linux
+
+package comport
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPortsInfoFromSysfs(t *testing.T) {
+	root, err := ioutil.TempDir("", "comport-sysfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	sysRoot := filepath.Join(root, "sys")
+	devRoot := filepath.Join(root, "dev")
+
+	usbDevice := "sys/devices/pci0000:00/usb1/1-1"
+	for _, x := range []struct{ path, content string }{
+		{usbDevice + "/idVendor", "0403\n"},
+		{usbDevice + "/idProduct", "6001\n"},
+		{usbDevice + "/serial", "A600ABCD\n"},
+		{usbDevice + "/manufacturer", "FTDI\n"},
+		{usbDevice + "/product", "FT232R USB UART\n"},
+		{usbDevice + "/1-1:1.0/ttyUSB0/tty/ttyUSB0/dev", "188:0\n"},
+		{"sys/devices/pci0000:00/usb1/1-2/idVendor", "2341\n"},
+		{"sys/devices/pci0000:00/usb1/1-2/idProduct", "0043\n"},
+		{"sys/devices/pci0000:00/usb1/1-2/1-2:1.0/tty/ttyACM0/dev", "166:0\n"},
+		{"sys/devices/platform/serial8250/tty/ttyS0/type", "4\n"},
+		{"sys/devices/platform/serial8250/tty/ttyS1/type", "0\n"},
+		{"sys/devices/virtual/tty/tty0/dev", "4:0\n"},
+		{"sys/bus/usb-serial/drivers/ftdi_sio/uevent", ""},
+		{"sys/bus/usb/drivers/cdc_acm/uevent", ""},
+		{"sys/bus/platform/drivers/serial8250/uevent", ""},
+		{"dev/ttyUSB0", ""},
+		{"dev/ttyACM0", ""},
+	} {
+		writeTestFile(t, filepath.Join(root, x.path), x.content)
+	}
+
+	for _, x := range []struct{ link, target string }{
+		{"sys/class/tty/ttyUSB0", "../../devices/pci0000:00/usb1/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0"},
+		{usbDevice + "/1-1:1.0/ttyUSB0/tty/ttyUSB0/device", "../../../ttyUSB0"},
+		{usbDevice + "/1-1:1.0/ttyUSB0/driver", "../../../../../../bus/usb-serial/drivers/ftdi_sio"},
+		{"sys/class/tty/ttyACM0", "../../devices/pci0000:00/usb1/1-2/1-2:1.0/tty/ttyACM0"},
+		{"sys/devices/pci0000:00/usb1/1-2/1-2:1.0/tty/ttyACM0/device", "../../../1-2:1.0"},
+		{"sys/devices/pci0000:00/usb1/1-2/1-2:1.0/driver", "../../../../../bus/usb/drivers/cdc_acm"},
+		{"sys/class/tty/ttyS0", "../../devices/platform/serial8250/tty/ttyS0"},
+		{"sys/class/tty/ttyS1", "../../devices/platform/serial8250/tty/ttyS1"},
+		{"sys/devices/platform/serial8250/tty/ttyS0/device", "../../../serial8250"},
+		{"sys/devices/platform/serial8250/tty/ttyS1/device", "../../../serial8250"},
+		{"sys/devices/platform/serial8250/driver", "../../../bus/platform/drivers/serial8250"},
+		{"sys/class/tty/tty0", "../../devices/virtual/tty/tty0"},
+		{"dev/serial/by-id/usb-FTDI_FT232R_USB_UART_A600ABCD-if00-port0", "../../ttyUSB0"},
+	} {
+		link := filepath.Join(root, x.link)
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(x.target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ports, err := PortsInfoFromSysfs(sysRoot, devRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := filepath.Join(devRoot, "serial/by-id/usb-FTDI_FT232R_USB_UART_A600ABCD-if00-port0")
+	want := []PortInfo{
+		{
+			Name:   filepath.Join(devRoot, "ttyACM0"),
+			VID:    0x2341,
+			PID:    0x0043,
+			Driver: "cdc_acm",
+		},
+		{
+			Name:   filepath.Join(devRoot, "ttyS0"),
+			Driver: "serial8250",
+		},
+		{
+			Name:         filepath.Join(devRoot, "ttyUSB0"),
+			ByID:         byID,
+			VID:          0x0403,
+			PID:          0x6001,
+			SerialNumber: "A600ABCD",
+			Manufacturer: "FTDI",
+			Product:      "FT232R USB UART",
+			Driver:       "ftdi_sio",
+		},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Fatalf("\ngot  %+v\nwant %+v", ports, want)
+	}
+
+	for _, name := range []string{filepath.Join(devRoot, "ttyUSB0"), byID, filepath.Join(devRoot, "ttyS0")} {
+		if err := checkPortNameIsValid(name, ports); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, name := range []string{filepath.Join(devRoot, "ttyS1"), filepath.Join(devRoot, "tty0")} {
+		if err := checkPortNameIsValid(name, ports); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
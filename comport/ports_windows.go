@@ -0,0 +1,136 @@
+//go:build windows
+// +build windows
+
+package comport
+
+import (
+	"golang.org/x/sys/windows/registry"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PortsInfo возвращает сведения о СОМ портах, представленных в системе
+func PortsInfo() ([]PortInfo, error) {
+
+	root, err := registry.OpenKey(registry.LOCAL_MACHINE, serialCommKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = root.Close()
+	}()
+
+	ks, err := root.ReadValueNames(0)
+	if err != nil {
+		return nil, err
+	}
+	usb := usbPortsInfo()
+	var ports []PortInfo
+	for _, k := range ks {
+		port, _, err := root.GetStringValue(k)
+		if err != nil {
+			return nil, err
+		}
+		p, f := usb[port]
+		if !f {
+			p.Driver = serialCommDriver(k)
+		}
+		p.Name = port
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// serialCommDriver возвращает имя драйвера по имени устройства из serialCommKey: \Device\VCP0 -> VCP
+func serialCommDriver(device string) string {
+	device = device[strings.LastIndex(device, `\`)+1:]
+	return strings.TrimRight(device, "0123456789")
+}
+
+// usbPortsInfo возвращает сведения о USB устройствах, предоставляющих СОМ порты, по именам портов
+func usbPortsInfo() map[string]PortInfo {
+	ports := make(map[string]PortInfo)
+	for _, enumKey := range usbEnumKeys {
+		root, err := registry.OpenKey(registry.LOCAL_MACHINE, enumKey, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			continue
+		}
+		devices, _ := root.ReadSubKeyNames(-1)
+		_ = root.Close()
+		for _, device := range devices {
+			m := regexVidPid.FindStringSubmatch(strings.ToUpper(device))
+			if m == nil {
+				continue
+			}
+			vid, _ := strconv.ParseUint(m[1], 16, 16)
+			pid, _ := strconv.ParseUint(m[2], 16, 16)
+			// FTDIBUS: VID_0403+PID_6001+A600ABCDA
+			serialNumber := strings.TrimSuffix(m[3], "A")
+			readUSBDeviceInstances(enumKey+`\`+device, func(instance string, p PortInfo) {
+				p.VID, p.PID = uint16(vid), uint16(pid)
+				p.SerialNumber = serialNumber
+				// USB: VID_0403&PID_6001\A600ABCD, instance of composite device contains '&'
+				if len(p.SerialNumber) == 0 && !strings.Contains(instance, "&") {
+					p.SerialNumber = instance
+				}
+				ports[p.Name] = p
+			})
+		}
+	}
+	return ports
+}
+
+func readUSBDeviceInstances(deviceKey string, f func(instance string, p PortInfo)) {
+	root, err := registry.OpenKey(registry.LOCAL_MACHINE, deviceKey, registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		return
+	}
+	instances, _ := root.ReadSubKeyNames(-1)
+	_ = root.Close()
+
+	for _, instance := range instances {
+		k, err := registry.OpenKey(registry.LOCAL_MACHINE, deviceKey+`\`+instance, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		var p PortInfo
+		p.Manufacturer = registryStringValue(k, "Mfg")
+		p.Product = registryStringValue(k, "DeviceDesc")
+		p.Driver = registryStringValue(k, "Service")
+		_ = k.Close()
+
+		k, err = registry.OpenKey(registry.LOCAL_MACHINE, deviceKey+`\`+instance+`\Device Parameters`, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		p.Name = registryStringValue(k, "PortName")
+		_ = k.Close()
+		if len(p.Name) > 0 {
+			f(instance, p)
+		}
+	}
+}
+
+// registryStringValue возвращает строковое значение ключа реестра.
+// Локализуемые значения вида "@oem.inf,%ftdi%;FTDI" возвращаются без ссылки на ресурс
+func registryStringValue(k registry.Key, name string) string {
+	s, _, err := k.GetStringValue(name)
+	if err != nil {
+		return ""
+	}
+	if strings.HasPrefix(s, "@") {
+		s = s[strings.LastIndex(s, ";")+1:]
+	}
+	return s
+}
+
+const serialCommKey = `hardware\devicemap\serialcomm`
+
+var (
+	usbEnumKeys = []string{
+		`SYSTEM\CurrentControlSet\Enum\USB`,
+		`SYSTEM\CurrentControlSet\Enum\FTDIBUS`,
+	}
+	regexVidPid = regexp.MustCompile(`VID_([0-9A-F]{4})[&+]PID_([0-9A-F]{4})(?:\+([0-9A-Z]+))?`)
+)
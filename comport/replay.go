@@ -0,0 +1,114 @@
+package comport
+
+import (
+	"bytes"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded - запрос, отправленный в ReplayPort, отсутствует в записи
+var ErrNotRecorded = merry.New("запрос отсутствует в записи")
+
+// ReplayPort отвечает на запросы ответами из записи приёмопередачи, например считанной comm.ReadRecordingFile.
+// Ответ становится доступным для чтения через записанное время ответа Info.Duration.
+// На запросы, ответ на которые не был получен, ReplayPort не отвечает.
+// Если strict == false, на запрос выдаётся первый неиспользованный ответ на такой же запрос,
+// после использования всех ответов на запрос они выдаются повторно в том же порядке.
+// Если strict == true, запросы должны следовать в порядке записи
+type ReplayPort struct {
+	mu         sync.Mutex
+	records    []comm.Info
+	strict     bool
+	served     []int
+	next       int
+	response   []byte
+	readyAt    time.Time
+	unrecorded [][]byte
+}
+
+func NewReplayPort(records []comm.Info, strict bool) *ReplayPort {
+	return &ReplayPort{
+		records: records,
+		strict:  strict,
+		served:  make([]int, len(records)),
+	}
+}
+
+func (x *ReplayPort) Write(p []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.response = nil
+	n := x.find(p)
+	if n < 0 {
+		x.unrecorded = append(x.unrecorded, append([]byte(nil), p...))
+		return 0, merry.Appendf(ErrNotRecorded, "% X", p)
+	}
+	x.served[n]++
+	x.next = n + 1
+	r := x.records[n]
+	if len(r.Response) > 0 {
+		x.response = append([]byte(nil), r.Response...)
+		x.readyAt = time.Now().Add(r.Duration)
+	}
+	return len(p), nil
+}
+
+func (x *ReplayPort) Available() (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if time.Now().Before(x.readyAt) {
+		return 0, nil
+	}
+	return len(x.response), nil
+}
+
+func (x *ReplayPort) Read(p []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if time.Now().Before(x.readyAt) {
+		return 0, nil
+	}
+	n := copy(p, x.response)
+	x.response = x.response[n:]
+	return n, nil
+}
+
+// Unrecorded возвращает запросы, отсутствующие в записи
+func (x *ReplayPort) Unrecorded() [][]byte {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return append([][]byte(nil), x.unrecorded...)
+}
+
+// Unused возвращает записи, которые не были использованы для ответа
+func (x *ReplayPort) Unused() []comm.Info {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var xs []comm.Info
+	for n, r := range x.records {
+		if x.served[n] == 0 {
+			xs = append(xs, r)
+		}
+	}
+	return xs
+}
+
+// find возвращает индекс записи с ответом на запрос request либо -1
+func (x *ReplayPort) find(request []byte) int {
+	if x.strict {
+		if x.next < len(x.records) && bytes.Equal(x.records[x.next].Request, request) {
+			return x.next
+		}
+		return -1
+	}
+	// ответ, выданный наименьшее число раз
+	found := -1
+	for n, r := range x.records {
+		if bytes.Equal(r.Request, request) && (found < 0 || x.served[n] < x.served[found]) {
+			found = n
+		}
+	}
+	return found
+}
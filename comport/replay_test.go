@@ -0,0 +1,79 @@
+package comport
+
+import (
+	"bytes"
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"testing"
+	"time"
+)
+
+func TestReplayPort(t *testing.T) {
+	records := []comm.Info{
+		{Request: []byte{1}, Err: context.DeadlineExceeded, Duration: 10 * time.Millisecond},
+		{Request: []byte{1}, Response: []byte{0x11}, Duration: 20 * time.Millisecond},
+		{Request: []byte{2}, Response: []byte{0x22}, Duration: time.Millisecond},
+	}
+	cfg := comm.Config{
+		TimeoutGetResponse: 50 * time.Millisecond,
+		MaxAttemptsRead:    2,
+	}
+
+	port := NewReplayPort(records, false)
+	cm := comm.New(port, cfg)
+
+	t0 := time.Now()
+	resp, err := cm.GetResponse(nil, context.Background(), []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp, []byte{0x11}) {
+		t.Errorf("unexpected response % X", resp)
+	}
+	// таймаут первой попытки и записанное время ответа второй
+	if d := time.Since(t0); d < 70*time.Millisecond {
+		t.Errorf("recorded timing is not reproduced: %v", d)
+	}
+
+	resp, err = cm.GetResponse(nil, context.Background(), []byte{2})
+	if err != nil || !bytes.Equal(resp, []byte{0x22}) {
+		t.Errorf("unexpected response % X: %v", resp, err)
+	}
+
+	_, err = cm.GetResponse(nil, context.Background(), []byte{3})
+	if !merry.Is(err, ErrNotRecorded) {
+		t.Errorf("ErrNotRecorded expected: %v", err)
+	}
+	if xs := port.Unrecorded(); len(xs) != 1 || !bytes.Equal(xs[0], []byte{3}) {
+		t.Errorf("unexpected unrecorded requests % X", xs)
+	}
+	if xs := port.Unused(); len(xs) != 0 {
+		t.Errorf("unexpected unused records %+v", xs)
+	}
+}
+
+func TestReplayPortStrict(t *testing.T) {
+	records := []comm.Info{
+		{Request: []byte{1}, Response: []byte{0x11}},
+		{Request: []byte{2}, Response: []byte{0x22}},
+	}
+	cm := comm.New(NewReplayPort(records, true), comm.Config{
+		TimeoutGetResponse: 50 * time.Millisecond,
+	})
+	if _, err := cm.GetResponse(nil, context.Background(), []byte{2}); !merry.Is(err, ErrNotRecorded) {
+		t.Errorf("ErrNotRecorded expected: %v", err)
+	}
+
+	port := NewReplayPort(records, true)
+	cm = cm.WithReadWriter(port)
+	for _, r := range records {
+		resp, err := cm.GetResponse(nil, context.Background(), r.Request)
+		if err != nil || !bytes.Equal(resp, r.Response) {
+			t.Errorf("unexpected response % X: %v", resp, err)
+		}
+	}
+	if _, err := cm.GetResponse(nil, context.Background(), []byte{1}); !merry.Is(err, ErrNotRecorded) {
+		t.Errorf("ErrNotRecorded expected after end of recording: %v", err)
+	}
+}
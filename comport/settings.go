@@ -0,0 +1,102 @@
+package comport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/internal"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+)
+
+// Settings - параметры СОМ портов и приёмопередачи через них
+type Settings struct {
+	Ports []PortSettings `json:"ports" yaml:"ports"`
+}
+
+// PortSettings - параметры СОМ порта и приёмопередачи через него.
+// Значения отсутствующих полей берутся из DefaultConfig и comm.DefaultConfig
+type PortSettings struct {
+	Port Config      `json:"port" yaml:"port"`
+	Comm comm.Config `json:"comm" yaml:"comm"`
+}
+
+// LoadSettings считывает параметры портов из документа YAML или JSON и проверяет их.
+// Длительности задаются строками, например "150ms"
+func LoadSettings(r io.Reader) (Settings, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Settings{}, merry.Wrap(err)
+	}
+	var x Settings
+	// JSON является подмножеством YAML
+	if err := yaml.UnmarshalStrict(b, &x); err != nil {
+		return Settings{}, comm.ErrConfig.Here().WithCause(err)
+	}
+	if err := x.Validate(); err != nil {
+		return x, err
+	}
+	return x, nil
+}
+
+// LoadSettingsFile считывает параметры портов из файла filename
+func LoadSettingsFile(filename string) (Settings, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Settings{}, merry.Wrap(err)
+	}
+	x, err := LoadSettings(bytes.NewReader(b))
+	return x, merry.Prepend(err, filename)
+}
+
+// Validate проверяет параметры всех портов, ошибка перечисляет все неверные поля
+func (x Settings) Validate() error {
+	var errs internal.FieldErrors
+	names := make(map[string]int)
+	for i, p := range x.Ports {
+		field := fmt.Sprintf("ports[%d]", i)
+		errs.AddErr(field, p.Validate())
+		if n, ok := names[p.Port.Name]; ok && len(p.Port.Name) > 0 {
+			errs.Add(field+".port.name", "повторяет ports[%d]: %s", n, p.Port.Name)
+		}
+		names[p.Port.Name] = i
+	}
+	return errs.Err(comm.ErrConfig)
+}
+
+// Validate проверяет параметры порта и приёмопередачи, ошибка перечисляет все неверные поля
+func (x PortSettings) Validate() error {
+	var errs internal.FieldErrors
+	errs.AddErr("port", x.Port.Validate())
+	errs.AddErr("comm", x.Comm.Validate())
+	return errs.Err(comm.ErrConfig)
+}
+
+func (x *PortSettings) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain PortSettings
+	p := plain{
+		Port: DefaultConfig(""),
+		Comm: comm.DefaultConfig(),
+	}
+	if err := unmarshal(&p); err != nil {
+		return err
+	}
+	*x = PortSettings(p)
+	return nil
+}
+
+func (x *PortSettings) UnmarshalJSON(b []byte) error {
+	type plain PortSettings
+	p := plain{
+		Port: DefaultConfig(""),
+		Comm: comm.DefaultConfig(),
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*x = PortSettings(p)
+	return nil
+}
@@ -0,0 +1,140 @@
+package comport
+
+import (
+	"encoding/json"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSettings(t *testing.T) {
+	for _, doc := range []string{
+		`
+ports:
+  - port:
+      name: /dev/ttyUSB0
+      baud: 19200
+      parity: even
+      stop_bits: 1.5
+    comm:
+      timeout_get_response: 150ms
+      echo: true
+  - port:
+      name: COM3
+`,
+		`{"ports": [
+	{"port": {"name": "/dev/ttyUSB0", "baud": 19200, "parity": "E", "stop_bits": "1.5"},
+	 "comm": {"timeout_get_response": "150ms", "echo": true}},
+	{"port": {"name": "COM3"}}
+]}`,
+	} {
+		x, err := LoadSettings(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(x.Ports) != 2 {
+			t.Fatalf("2 ports expected: %+v", x)
+		}
+		wantComm := comm.DefaultConfig()
+		wantComm.TimeoutGetResponse = 150 * time.Millisecond
+		wantComm.Echo = true
+		wantPort := DefaultConfig("/dev/ttyUSB0")
+		wantPort.Baud = 19200
+		wantPort.Parity = ParityEven
+		wantPort.StopBits = Stop1Half
+		if p := x.Ports[0]; p.Port != wantPort || p.Comm != wantComm {
+			t.Errorf("unexpected settings %+v", p)
+		}
+		if p := x.Ports[1]; p.Port != DefaultConfig("COM3") || p.Comm != comm.DefaultConfig() {
+			t.Errorf("defaults expected: %+v", p)
+		}
+	}
+}
+
+func TestLoadSettingsInvalid(t *testing.T) {
+	_, err := LoadSettings(strings.NewReader(`
+ports:
+  - port: {name: COM1, baud: 0, size: 9}
+    comm: {timeout_get_response: 0s}
+  - port: {name: COM1}
+`))
+	if !merry.Is(err, comm.ErrConfig) {
+		t.Fatalf("ErrConfig expected: %v", err)
+	}
+	for _, s := range []string{
+		"ports[0].port.baud",
+		"ports[0].port.size",
+		"ports[0].comm.timeout_get_response",
+		"ports[1].port.name",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("%s is not reported: %v", s, err)
+		}
+	}
+
+	_, err = LoadSettings(strings.NewReader(`{"ports": [{"port": {"name": "COM1", "parity": "X"}}]}`))
+	if !merry.Is(err, comm.ErrConfig) {
+		t.Errorf("ErrConfig expected: %v", err)
+	}
+}
+
+func TestNumericParity(t *testing.T) {
+	for _, x := range []struct {
+		value  string
+		parity Parity
+		ok     bool
+	}{
+		{"69", ParityEven, true},
+		{"78", ParityNone, true},
+		{"7", 0, false},
+	} {
+		// YAML и JSON принимают одни и те же значения
+		settings, err := LoadSettings(strings.NewReader("ports: [{port: {name: COM1, parity: " + x.value + "}}]"))
+		if (err == nil) != x.ok {
+			t.Errorf("YAML %s: unexpected error %v", x.value, err)
+		} else if x.ok && settings.Ports[0].Port.Parity != x.parity {
+			t.Errorf("YAML %s: parity %v, expected %v", x.value, settings.Ports[0].Port.Parity, x.parity)
+		}
+		if err != nil && strings.Count(err.Error(), "неизвестное значение чётности") != 1 {
+			t.Errorf("YAML %s: cause must be reported once: %v", x.value, err)
+		}
+
+		var c Config
+		err = json.Unmarshal([]byte(`{"parity":`+x.value+`}`), &c)
+		if (err == nil) != x.ok {
+			t.Errorf("JSON %s: unexpected error %v", x.value, err)
+		} else if x.ok && c.Parity != x.parity {
+			t.Errorf("JSON %s: parity %v, expected %v", x.value, c.Parity, x.parity)
+		}
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	c := DefaultConfig("COM1")
+	c.StopBits = Stop1Half
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"name":"COM1","baud":9600,"read_timeout":"1ms","size":8,"parity":"none","stop_bits":"1.5"}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+	var c2 Config
+	if err := json.Unmarshal(b, &c2); err != nil {
+		t.Fatal(err)
+	}
+	if c2 != c {
+		t.Errorf("got %+v, want %+v", c2, c)
+	}
+
+	// прежний формат с числовыми значениями
+	if err := json.Unmarshal([]byte(`{"parity":69,"stop_bits":2,"read_timeout":1000000}`), &c2); err != nil {
+		t.Fatal(err)
+	}
+	if c2.Parity != ParityEven || c2.StopBits != Stop2 || c2.ReadTimeout != time.Millisecond {
+		t.Errorf("unexpected config %+v", c2)
+	}
+}
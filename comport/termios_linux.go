@@ -0,0 +1,274 @@
+//go:build linux
+// +build linux
+
+package comport
+
+import (
+	"github.com/ansel1/merry"
+	"golang.org/x/sys/unix"
+	"sync"
+	"time"
+)
+
+type port struct {
+	fd          int
+	rl          sync.Mutex
+	wl          sync.Mutex
+	readTimeout time.Duration
+}
+
+func (p *port) Close() error {
+	return unix.Close(p.fd)
+}
+
+func (p *port) Write(buf []byte) (int, error) {
+	n, err := p.write(buf)
+	if err != nil {
+		return n, merry.Appendf(err, "written count: %d", n)
+	}
+	return n, nil
+}
+
+func (p *port) Read(buf []byte) (int, error) {
+	n, err := p.read(buf)
+	if err != nil {
+		return n, merry.Appendf(err, "read count: %d", n)
+	}
+	return n, nil
+}
+
+// Discards data written to the port but not transmitted,
+// or data received but not read
+func (p *port) Flush() error {
+	return unix.IoctlSetInt(p.fd, unix.TCFLSH, unix.TCIOFLUSH)
+}
+
+func (p *port) BytesToReadCount() (int, error) {
+	n, err := unix.IoctlGetInt(p.fd, unix.TIOCINQ)
+	if err != nil {
+		return 0, merry.Prepend(err, "FIONREAD: не удалось получить количество доступных для чтения байт")
+	}
+	return n, nil
+}
+
+// openPort opens a serial port with the specified configuration
+func openPort(c *Config) (*port, error) {
+	size, par, stop := c.Size, c.Parity, c.StopBits
+	if size == 0 {
+		size = DefaultSize
+	}
+	if par == 0 {
+		par = ParityNone
+	}
+	if stop == 0 {
+		stop = Stop1
+	}
+	return openPort2(c.Name, c.Baud, size, par, stop, c.ReadTimeout)
+}
+
+func openPort2(name string, baud int, databits byte, parity Parity, stopbits StopBits, readTimeout time.Duration) (*port, error) {
+	fd, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		switch err {
+		case unix.ENOENT, unix.ENXIO, unix.ENODEV:
+			err = merry.New("нет СОМ порта с таким именем")
+		case unix.EBUSY:
+			err = merry.New("СОМ порт занят")
+		case unix.EACCES, unix.EPERM:
+			err = merry.New("нет доступа к СОМ порту")
+		}
+		return nil, err
+	}
+
+	// exclusive mode: subsequent open calls fail with EBUSY, as with windows CreateFile without sharing
+	if err = unix.IoctlSetInt(fd, unix.TIOCEXCL, 0); err != nil {
+		_ = unix.Close(fd)
+		return nil, merry.Prepend(err, "TIOCEXCL")
+	}
+	if err = setTermios(fd, baud, databits, parity, stopbits); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	port := new(port)
+	port.fd = fd
+	port.readTimeout = readTimeout
+	if err = port.Flush(); err != nil {
+		_ = unix.Close(fd)
+		return nil, err
+	}
+	return port, nil
+}
+
+func (p *port) write(buf []byte) (int, error) {
+	p.wl.Lock()
+	defer p.wl.Unlock()
+
+	if err := p.Flush(); err != nil {
+		return 0, merry.Appendf(err, "attempt to write: % X", buf)
+	}
+
+	var written int
+	for written < len(buf) {
+		n, err := unix.Write(p.fd, buf[written:])
+		if n > 0 {
+			written += n
+		}
+		if err == unix.EAGAIN || err == unix.EINTR {
+			if err = p.poll(unix.POLLOUT, -1); err != nil {
+				return written, merry.Appendf(err, "attempt to write: % X", buf)
+			}
+			continue
+		}
+		if err != nil {
+			return written, merry.Appendf(err, "attempt to write: % X", buf)
+		}
+	}
+	return written, nil
+}
+
+func (p *port) read(buf []byte) (int, error) {
+	if p == nil {
+		return 0, merry.New("invalid port on read")
+	}
+
+	p.rl.Lock()
+	defer p.rl.Unlock()
+
+	n, err := unix.Read(p.fd, buf)
+	if err == unix.EAGAIN || err == unix.EINTR {
+		// no bytes in the input buffer: wait until a byte arrives
+		// or ReadTimeout elapses, the same way as windows ReadFile does
+		if err = p.poll(unix.POLLIN, p.readTimeout); err != nil {
+			return 0, err
+		}
+		n, err = unix.Read(p.fd, buf)
+		if err == unix.EAGAIN || err == unix.EINTR {
+			return 0, nil
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// poll waits for the events on the port's file descriptor.
+// Zero or negative timeout means infinite waiting.
+func (p *port) poll(events int16, timeout time.Duration) error {
+	timeoutMs := -1
+	if timeout > 0 {
+		timeoutMs = int(timeout / time.Millisecond)
+		if timeoutMs < 1 {
+			timeoutMs = 1
+		}
+	}
+	fds := []unix.PollFd{{Fd: int32(p.fd), Events: events}}
+	for {
+		_, err := unix.Poll(fds, timeoutMs)
+		if err == unix.EINTR {
+			continue
+		}
+		return err
+	}
+}
+
+func setTermios(fd int, baud int, databits byte, parity Parity, stopbits StopBits) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return merry.Prepend(err, "TCGETS")
+	}
+
+	// raw mode
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL |
+		unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CMSPAR | unix.CSTOPB | unix.CRTSCTS
+	t.Cflag |= unix.CREAD | unix.CLOCAL
+
+	// non-blocking read
+	t.Cc[unix.VMIN] = 0
+	t.Cc[unix.VTIME] = 0
+
+	rate, ok := baudRates[baud]
+	if !ok {
+		return merry.Errorf("unsupported baud rate %d", baud)
+	}
+	t.Cflag &^= unix.CBAUD
+	t.Cflag |= rate
+	t.Ispeed = rate
+	t.Ospeed = rate
+
+	switch databits {
+	case 5:
+		t.Cflag |= unix.CS5
+	case 6:
+		t.Cflag |= unix.CS6
+	case 7:
+		t.Cflag |= unix.CS7
+	case 8:
+		t.Cflag |= unix.CS8
+	default:
+		return merry.New("unsupported data bits setting")
+	}
+
+	switch parity {
+	case ParityNone:
+	case ParityOdd:
+		t.Cflag |= unix.PARENB | unix.PARODD
+	case ParityEven:
+		t.Cflag |= unix.PARENB
+	case ParityMark:
+		t.Cflag |= unix.PARENB | unix.PARODD | unix.CMSPAR
+	case ParitySpace:
+		t.Cflag |= unix.PARENB | unix.CMSPAR
+	default:
+		return merry.New("unsupported parity setting")
+	}
+
+	switch stopbits {
+	case Stop1:
+	case Stop2:
+		t.Cflag |= unix.CSTOPB
+	default:
+		return merry.New("unsupported stop bit setting")
+	}
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return merry.Prepend(err, "TCSETS")
+	}
+	return nil
+}
+
+var baudRates = map[int]uint32{
+	50:      unix.B50,
+	75:      unix.B75,
+	110:     unix.B110,
+	134:     unix.B134,
+	150:     unix.B150,
+	200:     unix.B200,
+	300:     unix.B300,
+	600:     unix.B600,
+	1200:    unix.B1200,
+	1800:    unix.B1800,
+	2400:    unix.B2400,
+	4800:    unix.B4800,
+	9600:    unix.B9600,
+	19200:   unix.B19200,
+	38400:   unix.B38400,
+	57600:   unix.B57600,
+	115200:  unix.B115200,
+	230400:  unix.B230400,
+	460800:  unix.B460800,
+	500000:  unix.B500000,
+	576000:  unix.B576000,
+	921600:  unix.B921600,
+	1000000: unix.B1000000,
+	1152000: unix.B1152000,
+	1500000: unix.B1500000,
+	2000000: unix.B2000000,
+	2500000: unix.B2500000,
+	3000000: unix.B3000000,
+	3500000: unix.B3500000,
+	4000000: unix.B4000000,
+}
@@ -0,0 +1,124 @@
+//go:build linux
+// +build linux
+
+package comport
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPortPseudoTerminal(t *testing.T) {
+	master, slaveName := openPseudoTerminal(t)
+	defer master.Close()
+
+	p := NewPort(Config{Name: slaveName, Baud: 9600})
+	defer p.Close()
+
+	request := []byte{1, 3, 0, 0, 0, 2, 0xC4, 0x0B}
+	if n, err := p.Write(request); err != nil || n != len(request) {
+		t.Fatalf("write: %d, %v", n, err)
+	}
+	b := make([]byte, len(request))
+	if _, err := master.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, request) {
+		t.Fatalf("master got % X, want % X", b, request)
+	}
+
+	response := []byte{1, 3, 4, 0x41, 0x20, 0, 0, 0xEE, 0x3B}
+	if _, err := master.Write(response); err != nil {
+		t.Fatal(err)
+	}
+	n := waitBytesToRead(t, p, len(response))
+	b = make([]byte, n)
+	if _, err := p.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, response) {
+		t.Fatalf("port got % X, want % X", b, response)
+	}
+	if n, err := p.Read(nil); err != nil || n != 0 {
+		t.Fatalf("bytes to read after read: %d, %v", n, err)
+	}
+}
+
+func TestPortUnsupportedSettings(t *testing.T) {
+	master, slaveName := openPseudoTerminal(t)
+	defer master.Close()
+
+	for _, c := range []Config{
+		{Name: slaveName, Baud: 12345},
+		{Name: slaveName, Baud: 9600, Size: 9},
+		{Name: slaveName, Baud: 9600, Parity: 'X'},
+		{Name: slaveName, Baud: 9600, StopBits: Stop1Half},
+	} {
+		p := NewPort(c)
+		if _, err := p.Read(nil); err == nil {
+			t.Errorf("%+v: error expected", c)
+		}
+		_ = p.Close()
+	}
+}
+
+func TestPortNotExists(t *testing.T) {
+	p := NewPort(Config{Name: "/dev/ttyNotExists", Baud: 9600})
+	if _, err := p.Write([]byte{1}); err == nil {
+		t.Fatal("error expected")
+	}
+}
+
+func waitBytesToRead(t *testing.T, p *Port, count int) int {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		n, err := p.Read(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n >= count {
+			return n
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d bytes to read, %d expected", n, count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func openPseudoTerminal(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		t.Skip("pseudo terminal is not available:", err)
+	}
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+	// raw mode on the master side so that the bytes written by the test are passed as is
+	tio, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+	tio.Iflag = 0
+	tio.Oflag = 0
+	tio.Lflag = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, tio); err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
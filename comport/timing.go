@@ -0,0 +1,70 @@
+package comport
+
+import (
+	"github.com/fpawel/comm"
+	"time"
+)
+
+// Timing - временные параметры приёмопередачи Modbus RTU, зависящие от параметров СОМ порта
+type Timing struct {
+	Char           time.Duration // время передачи одного символа
+	InterCharacter time.Duration // наибольшая пауза между символами кадра, 1.5 символа
+	InterFrame     time.Duration // наименьшая пауза между кадрами, 3.5 символа
+	Turnaround     time.Duration // наименьшая пауза после запроса, на который не ожидается ответ
+}
+
+// Timing возвращает временные параметры, рассчитанные по скорости, числу бит данных, чётности и стоп-битов.
+// При скорости больше 19200 бод паузы между символами и кадрами фиксированы: 750 мкс и 1750 мкс
+func (c Config) Timing() Timing {
+	if c.Baud <= 0 {
+		return Timing{}
+	}
+	baud := time.Duration(c.Baud)
+	// длительность символа в половинах бита: старт-бит, биты данных, бит чётности и стоп-биты
+	halfBits := 2 * (1 + time.Duration(c.dataBits()))
+	if c.Parity != 0 && c.Parity != ParityNone {
+		halfBits += 2
+	}
+	switch c.StopBits {
+	case Stop1Half:
+		halfBits += 3
+	case Stop2:
+		halfBits += 4
+	default:
+		halfBits += 2
+	}
+	t := Timing{
+		Char:           time.Second * halfBits / (2 * baud),
+		InterCharacter: time.Second * 3 * halfBits / (4 * baud),
+		InterFrame:     time.Second * 7 * halfBits / (4 * baud),
+	}
+	if c.Baud > 19200 {
+		t.InterCharacter = 750 * time.Microsecond
+		t.InterFrame = 1750 * time.Microsecond
+	}
+	t.Turnaround = t.InterFrame
+	return t
+}
+
+// CommConfig возвращает параметры приёмопередачи cfg, в которых таймаут окончания ответа,
+// пауза перед запросом и пауза после запроса без ответа не меньше рассчитанных Timing
+func (c Config) CommConfig(cfg comm.Config) comm.Config {
+	t := c.Timing()
+	if cfg.TimeoutEndResponse < t.InterFrame {
+		cfg.TimeoutEndResponse = t.InterFrame
+	}
+	if cfg.Pause < t.InterFrame {
+		cfg.Pause = t.InterFrame
+	}
+	if cfg.TurnaroundDelay < t.Turnaround {
+		cfg.TurnaroundDelay = t.Turnaround
+	}
+	return cfg
+}
+
+func (c Config) dataBits() byte {
+	if c.Size == 0 {
+		return DefaultSize
+	}
+	return c.Size
+}
@@ -0,0 +1,70 @@
+package comport
+
+import (
+	"github.com/fpawel/comm"
+	"testing"
+	"time"
+)
+
+func TestConfigTiming(t *testing.T) {
+	for _, x := range []struct {
+		c    Config
+		want Timing
+	}{
+		{
+			Config{Baud: 9600},
+			Timing{Char: 1041666, InterCharacter: 1562500, InterFrame: 3645833, Turnaround: 3645833},
+		},
+		{
+			Config{Baud: 9600, Size: 8, Parity: ParityEven, StopBits: Stop1},
+			Timing{Char: 1145833, InterCharacter: 1718750, InterFrame: 4010416, Turnaround: 4010416},
+		},
+		{
+			Config{Baud: 9600, Size: 8, Parity: ParityNone, StopBits: Stop2},
+			Timing{Char: 1145833, InterCharacter: 1718750, InterFrame: 4010416, Turnaround: 4010416},
+		},
+		{
+			Config{Baud: 2400, Size: 7, Parity: ParityOdd, StopBits: Stop1Half},
+			Timing{Char: 4375000, InterCharacter: 6562500, InterFrame: 15312500, Turnaround: 15312500},
+		},
+		{
+			Config{Baud: 19200, Size: 8, Parity: ParityEven, StopBits: Stop1},
+			Timing{Char: 572916, InterCharacter: 859375, InterFrame: 2005208, Turnaround: 2005208},
+		},
+		{
+			Config{Baud: 38400},
+			Timing{Char: 260416, InterCharacter: 750 * time.Microsecond, InterFrame: 1750 * time.Microsecond, Turnaround: 1750 * time.Microsecond},
+		},
+		{
+			Config{Baud: 115200, Parity: ParityEven},
+			Timing{Char: 95486, InterCharacter: 750 * time.Microsecond, InterFrame: 1750 * time.Microsecond, Turnaround: 1750 * time.Microsecond},
+		},
+		{
+			Config{},
+			Timing{},
+		},
+	} {
+		if got := x.c.Timing(); got != x.want {
+			t.Errorf("%+v: got %+v, want %+v", x.c, got, x.want)
+		}
+	}
+}
+
+func TestConfigCommConfig(t *testing.T) {
+	c := Config{Baud: 9600}
+	got := c.CommConfig(comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 50 * time.Millisecond,
+		MaxAttemptsRead:    3,
+	})
+	want := comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 50 * time.Millisecond,
+		MaxAttemptsRead:    3,
+		Pause:              3645833,
+		TurnaroundDelay:    3645833,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
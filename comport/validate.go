@@ -0,0 +1,190 @@
+package comport
+
+import (
+	"encoding/json"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/internal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultConfig возвращает параметры СОМ порта name по умолчанию: 9600 бод, 8N1
+func DefaultConfig(name string) Config {
+	return Config{
+		Name:        name,
+		Baud:        9600,
+		ReadTimeout: time.Millisecond,
+		Size:        DefaultSize,
+		Parity:      ParityNone,
+		StopBits:    Stop1,
+	}
+}
+
+// Validate проверяет параметры СОМ порта, ошибка перечисляет все неверные поля
+func (c Config) Validate() error {
+	var errs internal.FieldErrors
+	if len(c.Name) == 0 {
+		errs.Add("name", "не задано имя СОМ порта")
+	}
+	if c.Baud <= 0 {
+		errs.Add("baud", "должна быть больше нуля: %d", c.Baud)
+	}
+	if c.ReadTimeout < 0 {
+		errs.Add("read_timeout", "не может быть отрицательным: %v", c.ReadTimeout)
+	}
+	if c.Size != 0 && (c.Size < 5 || c.Size > 8) {
+		errs.Add("size", "должно быть от 5 до 8: %d", c.Size)
+	}
+	if _, ok := parityNames[c.Parity]; !ok && c.Parity != 0 {
+		errs.Add("parity", "неизвестное значение %d", c.Parity)
+	}
+	if _, ok := stopBitsNames[c.StopBits]; !ok && c.StopBits != 0 {
+		errs.Add("stop_bits", "неизвестное значение %d", c.StopBits)
+	}
+	return errs.Err(comm.ErrConfig)
+}
+
+// MarshalJSON представляет ReadTimeout строкой, например "1ms"
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newConfigJSON(c))
+}
+
+// UnmarshalJSON принимает ReadTimeout строкой либо числом наносекунд.
+// Значения отсутствующих полей не изменяются
+func (c *Config) UnmarshalJSON(b []byte) error {
+	x := newConfigJSON(*c)
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	*c = Config{
+		Name:        x.Name,
+		Baud:        x.Baud,
+		ReadTimeout: time.Duration(x.ReadTimeout),
+		Size:        x.Size,
+		Parity:      x.Parity,
+		StopBits:    x.StopBits,
+	}
+	return nil
+}
+
+type configJSON struct {
+	Name        string            `json:"name"`
+	Baud        int               `json:"baud"`
+	ReadTimeout internal.Duration `json:"read_timeout"`
+	Size        byte              `json:"size"`
+	Parity      Parity            `json:"parity"`
+	StopBits    StopBits          `json:"stop_bits"`
+}
+
+func newConfigJSON(c Config) configJSON {
+	return configJSON{
+		Name:        c.Name,
+		Baud:        c.Baud,
+		ReadTimeout: internal.Duration(c.ReadTimeout),
+		Size:        c.Size,
+		Parity:      c.Parity,
+		StopBits:    c.StopBits,
+	}
+}
+
+func (x Parity) String() string {
+	if s, ok := parityNames[x]; ok {
+		return s
+	}
+	return strconv.Itoa(int(x))
+}
+
+func (x Parity) MarshalText() ([]byte, error) {
+	if x == 0 {
+		x = ParityNone
+	}
+	if _, ok := parityNames[x]; !ok {
+		return nil, merry.Errorf("неизвестное значение чётности %d", x)
+	}
+	return []byte(x.String()), nil
+}
+
+// UnmarshalText принимает название чётности, например "none" или "even", её букву, например "N" или "E",
+// либо код символа чётности прежнего формата, например "69"
+func (x *Parity) UnmarshalText(b []byte) error {
+	s := strings.ToLower(strings.TrimSpace(string(b)))
+	n, errN := strconv.ParseUint(s, 10, 8)
+	for p, name := range parityNames {
+		if s == name || s == strings.ToLower(string(p)) || errN == nil && n == uint64(p) {
+			*x = p
+			return nil
+		}
+	}
+	return merry.Errorf("неизвестное значение чётности %q", b)
+}
+
+// UnmarshalJSON принимает строку либо число, см. UnmarshalText
+func (x *Parity) UnmarshalJSON(b []byte) error {
+	if _, err := strconv.ParseUint(string(b), 10, 8); err == nil {
+		return x.UnmarshalText(b)
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return merry.Wrap(err)
+	}
+	return x.UnmarshalText([]byte(s))
+}
+
+func (x StopBits) String() string {
+	if s, ok := stopBitsNames[x]; ok {
+		return s
+	}
+	return strconv.Itoa(int(x))
+}
+
+func (x StopBits) MarshalText() ([]byte, error) {
+	if x == 0 {
+		x = Stop1
+	}
+	if _, ok := stopBitsNames[x]; !ok {
+		return nil, merry.Errorf("неизвестное количество стоп-битов %d", x)
+	}
+	return []byte(x.String()), nil
+}
+
+// UnmarshalText принимает количество стоп-битов "1", "1.5" или "2"
+func (x *StopBits) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	for v, name := range stopBitsNames {
+		if s == name {
+			*x = v
+			return nil
+		}
+	}
+	if s == "15" {
+		*x = Stop1Half
+		return nil
+	}
+	return merry.Errorf("неизвестное количество стоп-битов %q", b)
+}
+
+// UnmarshalJSON принимает количество стоп-битов строкой либо числом
+func (x *StopBits) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return x.UnmarshalText(b)
+	}
+	return x.UnmarshalText([]byte(s))
+}
+
+var (
+	parityNames = map[Parity]string{
+		ParityNone:  "none",
+		ParityOdd:   "odd",
+		ParityEven:  "even",
+		ParityMark:  "mark",
+		ParitySpace: "space",
+	}
+	stopBitsNames = map[StopBits]string{
+		Stop1:     "1",
+		Stop1Half: "1.5",
+		Stop2:     "2",
+	}
+)
@@ -0,0 +1,91 @@
+package comm
+
+import (
+	"encoding/json"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm/internal"
+	"time"
+)
+
+// ErrConfig - неверные параметры приёмопередачи или порта
+var ErrConfig = merry.New("неверные параметры")
+
+// DefaultConfig возвращает параметры приёмопередачи по умолчанию
+func DefaultConfig() Config {
+	return Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 50 * time.Millisecond,
+		MaxAttemptsRead:    3,
+	}
+}
+
+// Validate проверяет параметры приёмопередачи, ошибка перечисляет все неверные поля
+func (x Config) Validate() error {
+	var errs internal.FieldErrors
+	if x.TimeoutGetResponse <= 0 {
+		errs.Add("timeout_get_response", "должен быть больше нуля: %v", x.TimeoutGetResponse)
+	}
+	if x.MaxAttemptsRead < 1 {
+		errs.Add("max_attempts_read", "должно быть не меньше 1: %d", x.MaxAttemptsRead)
+	}
+	for _, f := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"timeout_end_response", x.TimeoutEndResponse},
+		{"pause", x.Pause},
+		{"total_timeout", x.TotalTimeout},
+		{"turnaround_delay", x.TurnaroundDelay},
+	} {
+		if f.d < 0 {
+			errs.Add(f.name, "не может быть отрицательным: %v", f.d)
+		}
+	}
+	return errs.Err(ErrConfig)
+}
+
+// MarshalJSON представляет длительности строками, например "150ms"
+func (x Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newConfigJSON(x))
+}
+
+// UnmarshalJSON принимает длительности строками либо числом наносекунд.
+// Значения отсутствующих полей не изменяются
+func (x *Config) UnmarshalJSON(b []byte) error {
+	c := newConfigJSON(*x)
+	if err := json.Unmarshal(b, &c); err != nil {
+		return err
+	}
+	*x = Config{
+		TimeoutGetResponse: time.Duration(c.TimeoutGetResponse),
+		TimeoutEndResponse: time.Duration(c.TimeoutEndResponse),
+		MaxAttemptsRead:    c.MaxAttemptsRead,
+		Pause:              time.Duration(c.Pause),
+		TotalTimeout:       time.Duration(c.TotalTimeout),
+		TurnaroundDelay:    time.Duration(c.TurnaroundDelay),
+		Echo:               c.Echo,
+	}
+	return nil
+}
+
+type configJSON struct {
+	TimeoutGetResponse internal.Duration `json:"timeout_get_response"`
+	TimeoutEndResponse internal.Duration `json:"timeout_end_response"`
+	MaxAttemptsRead    int               `json:"max_attempts_read"`
+	Pause              internal.Duration `json:"pause"`
+	TotalTimeout       internal.Duration `json:"total_timeout"`
+	TurnaroundDelay    internal.Duration `json:"turnaround_delay"`
+	Echo               bool              `json:"echo"`
+}
+
+func newConfigJSON(x Config) configJSON {
+	return configJSON{
+		TimeoutGetResponse: internal.Duration(x.TimeoutGetResponse),
+		TimeoutEndResponse: internal.Duration(x.TimeoutEndResponse),
+		MaxAttemptsRead:    x.MaxAttemptsRead,
+		Pause:              internal.Duration(x.Pause),
+		TotalTimeout:       internal.Duration(x.TotalTimeout),
+		TurnaroundDelay:    internal.Duration(x.TurnaroundDelay),
+		Echo:               x.Echo,
+	}
+}
@@ -0,0 +1,43 @@
+package comm
+
+import (
+	"encoding/json"
+	"github.com/ansel1/merry"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Error(err)
+	}
+	err := Config{Pause: -time.Second}.Validate()
+	if !merry.Is(err, ErrConfig) {
+		t.Fatalf("ErrConfig expected: %v", err)
+	}
+	for _, s := range []string{"timeout_get_response", "max_attempts_read", "pause"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("%s is not reported: %v", s, err)
+		}
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	c := DefaultConfig()
+	c.Pause = 150 * time.Millisecond
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"pause":"150ms"`) {
+		t.Errorf("duration string expected: %s", b)
+	}
+	var c2 Config
+	if err := json.Unmarshal(b, &c2); err != nil {
+		t.Fatal(err)
+	}
+	if c2 != c {
+		t.Errorf("got %+v, want %+v", c2, c)
+	}
+}
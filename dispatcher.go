@@ -0,0 +1,105 @@
+package comm
+
+import (
+	"sync"
+)
+
+// OverflowPolicy определяет поведение Dispatcher при переполнении очереди уведомлений
+type OverflowPolicy int
+
+const (
+	OverflowDropOldest OverflowPolicy = iota // отбросить самое старое уведомление в очереди
+	OverflowDropNewest                       // отбросить новое уведомление
+	OverflowBlock                            // ожидать освобождения места в очереди
+)
+
+const (
+	DefaultNotifyQueueSize = 1024          // размер очереди уведомлений SetNotify и T.WithNotify
+	DefaultOverflowPolicy  = OverflowBlock // поведение при переполнении очереди SetNotify и T.WithNotify, уведомления не теряются
+)
+
+// Dispatcher доставляет уведомления Info функции NotifyFunc в порядке их поступления
+// через очередь ограниченного размера.
+// Доставка выполняется отдельной горутиной, которая существует, пока очередь не пуста
+type Dispatcher struct {
+	f       NotifyFunc
+	size    int
+	policy  OverflowPolicy
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []Info
+	running bool
+	dropped uint64
+}
+
+// NewDispatcher создаёт Dispatcher с очередью размера queueSize.
+// Если queueSize < 1, используется DefaultNotifyQueueSize
+func NewDispatcher(f NotifyFunc, queueSize int, policy OverflowPolicy) *Dispatcher {
+	if queueSize < 1 {
+		queueSize = DefaultNotifyQueueSize
+	}
+	x := &Dispatcher{
+		f:      f,
+		size:   queueSize,
+		policy: policy,
+	}
+	x.cond = sync.NewCond(&x.mu)
+	return x
+}
+
+// Notify ставит уведомление в очередь доставки
+func (x *Dispatcher) Notify(i Info) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for len(x.queue) >= x.size {
+		switch x.policy {
+		case OverflowDropNewest:
+			x.dropped++
+			return
+		case OverflowBlock:
+			x.cond.Wait()
+		default:
+			x.queue[0] = Info{}
+			x.queue = x.queue[1:]
+			x.dropped++
+		}
+	}
+	x.queue = append(x.queue, i)
+	if !x.running {
+		x.running = true
+		go x.run()
+	}
+}
+
+// Dropped возвращает количество уведомлений, отброшенных из-за переполнения очереди
+func (x *Dispatcher) Dropped() uint64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.dropped
+}
+
+// Len возвращает количество уведомлений в очереди
+func (x *Dispatcher) Len() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return len(x.queue)
+}
+
+func (x *Dispatcher) run() {
+	for {
+		x.mu.Lock()
+		if len(x.queue) == 0 {
+			x.running = false
+			x.mu.Unlock()
+			return
+		}
+		i := x.queue[0]
+		x.queue[0] = Info{}
+		x.queue = x.queue[1:]
+		x.cond.Broadcast()
+		x.mu.Unlock()
+
+		x.f(i)
+	}
+}
@@ -0,0 +1,135 @@
+package comm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDispatcherOrder(t *testing.T) {
+	const count = 1000
+	c := make(chan int, count)
+	d := NewDispatcher(func(i Info) {
+		c <- i.Attempt
+	}, count, OverflowBlock)
+	for i := 0; i < count; i++ {
+		d.Notify(Info{Attempt: i})
+	}
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-c:
+			if n != i {
+				t.Fatalf("notification %d received, expected %d", n, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d was not received", i)
+		}
+	}
+	if d.Dropped() != 0 {
+		t.Errorf("dropped %d", d.Dropped())
+	}
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	for _, x := range []struct {
+		policy   OverflowPolicy
+		received []int
+	}{
+		{OverflowDropOldest, []int{0, 3, 4}},
+		{OverflowDropNewest, []int{0, 1, 2}},
+		{OverflowBlock, []int{0, 1, 2, 3, 4}},
+	} {
+		var (
+			release = make(chan struct{})
+			c       = make(chan int, 10)
+		)
+		d := NewDispatcher(func(i Info) {
+			<-release
+			c <- i.Attempt
+		}, 2, x.policy)
+
+		// первое уведомление извлекается из очереди и блокирует доставку
+		d.Notify(Info{Attempt: 0})
+		waitDispatcherLen(t, d, 0)
+
+		done := make(chan struct{})
+		go func() {
+			for i := 1; i < 5; i++ {
+				d.Notify(Info{Attempt: i})
+			}
+			close(done)
+		}()
+		if x.policy == OverflowBlock {
+			select {
+			case <-done:
+				t.Fatal("notify must block on overflow")
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		close(release)
+		<-done
+
+		var received []int
+		for range x.received {
+			select {
+			case n := <-c:
+				received = append(received, n)
+			case <-time.After(time.Second):
+				t.Fatalf("policy %d: received %v, expected %v", x.policy, received, x.received)
+			}
+		}
+		for i := range received {
+			if received[i] != x.received[i] {
+				t.Fatalf("policy %d: received %v, expected %v", x.policy, received, x.received)
+			}
+		}
+		if n := int(d.Dropped()); n != 5-len(x.received) {
+			t.Errorf("policy %d: dropped %d, expected %d", x.policy, n, 5-len(x.received))
+		}
+	}
+}
+
+func TestDefaultOverflowPolicyLossless(t *testing.T) {
+	const count = 10
+	var (
+		release = make(chan struct{})
+		c       = make(chan int, count)
+	)
+	d := NewDispatcher(func(i Info) {
+		<-release
+		c <- i.Attempt
+	}, 2, DefaultOverflowPolicy)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < count; i++ {
+			d.Notify(Info{Attempt: i})
+		}
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-done
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-c:
+			if n != i {
+				t.Fatalf("notification %d received, expected %d", n, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d was not received", i)
+		}
+	}
+	if d.Dropped() != 0 {
+		t.Errorf("dropped %d", d.Dropped())
+	}
+}
+
+func waitDispatcherLen(t *testing.T, d *Dispatcher, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for d.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue length %d, expected %d", d.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
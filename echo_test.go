@@ -0,0 +1,99 @@
+package comm_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEcho(t *testing.T) {
+	cfg := comm.Config{
+		TimeoutGetResponse: 100 * time.Millisecond,
+		TimeoutEndResponse: 10 * time.Millisecond,
+		Echo:               true,
+	}
+	reply := func(req []byte) []byte {
+		return append([]byte{0xAA}, req...)
+	}
+
+	cm := comm.New(&echoPort{reply: reply}, cfg)
+	resp, err := cm.GetResponse(nil, context.Background(), []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp, []byte{0xAA, 1, 2, 3}) {
+		t.Errorf("echo is not stripped: % X", resp)
+	}
+
+	// без Config.Echo ответ начинается с эха
+	resp, err = cm.WithConfig(comm.Config{
+		TimeoutGetResponse: 100 * time.Millisecond,
+		TimeoutEndResponse: 10 * time.Millisecond,
+		MaxAttemptsRead:    1,
+	}).GetResponse(nil, context.Background(), []byte{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp, []byte{1, 2, 3, 0xAA, 1, 2, 3}) {
+		t.Errorf("unexpected response % X", resp)
+	}
+
+	cm = comm.New(&echoPort{reply: reply, corrupt: true}, cfg)
+	_, err = cm.GetResponse(nil, context.Background(), []byte{1, 2, 3})
+	if !merry.Is(err, comm.ErrEcho) || !merry.Is(err, comm.Err) {
+		t.Errorf("ErrEcho expected: %v", err)
+	}
+
+	port := &echoPort{}
+	if err := comm.New(port, cfg).Send(nil, context.Background(), []byte{4, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := port.Available(); n != 0 {
+		t.Errorf("echo of sent request is not consumed: %d bytes", n)
+	}
+}
+
+func TestEchoSilentLine(t *testing.T) {
+	comm.ResetStats()
+	var requestsCount int32
+	cm := comm.New(&countingPort{n: &requestsCount}, comm.Config{
+		TimeoutGetResponse: 20 * time.Millisecond,
+		MaxAttemptsRead:    3,
+		Echo:               true,
+	}).WithLockPort("TestEchoSilentLine")
+	_, err := cm.GetResponse(nil, context.Background(), []byte{1, 2, 3})
+	if !merry.Is(err, context.DeadlineExceeded) || merry.Is(err, comm.ErrEcho) {
+		t.Errorf("timeout expected: %v", err)
+	}
+	// таймаут эха повторяется так же, как таймаут ответа
+	if n := atomic.LoadInt32(&requestsCount); n != 3 {
+		t.Errorf("%d requests, expected 3", n)
+	}
+	c := comm.Stats()["TestEchoSilentLine"].Counters
+	if c.Timeouts != 3 || c.Errors != 0 {
+		t.Errorf("unexpected stats %+v", c)
+	}
+}
+
+// echoPort возвращает эхо запроса, за которым следует ответ reply
+type echoPort struct {
+	pushPort
+	reply   func([]byte) []byte
+	corrupt bool
+}
+
+func (x *echoPort) Write(p []byte) (int, error) {
+	echo := append([]byte(nil), p...)
+	if x.corrupt {
+		echo[len(echo)/2] ^= 0xFF
+	}
+	x.push(echo...)
+	if x.reply != nil {
+		x.push(x.reply(p)...)
+	}
+	return len(p), nil
+}
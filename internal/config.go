@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ansel1/merry"
+	"strings"
+	"time"
+)
+
+// Duration - time.Duration, которая в JSON представляется строкой, например "150ms".
+// При чтении допускается также число наносекунд
+type Duration time.Duration
+
+func (x Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(x).String())
+}
+
+func (x *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return merry.Errorf("ожидалась длительность: %s", b)
+		}
+		*x = Duration(n)
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return merry.Wrap(err)
+	}
+	*x = Duration(d)
+	return nil
+}
+
+// FieldErrors накапливает ошибки значений полей
+type FieldErrors []string
+
+func (x *FieldErrors) Add(field string, format string, args ...interface{}) {
+	*x = append(*x, field+": "+fmt.Sprintf(format, args...))
+}
+
+// AddErr добавляет ошибки err, полученные при проверке вложенной структуры field
+func (x *FieldErrors) AddErr(field string, err error) {
+	if err == nil {
+		return
+	}
+	if fe, ok := merry.Value(err, fieldErrorsKey{}).(FieldErrors); ok {
+		for _, s := range fe {
+			*x = append(*x, field+"."+s)
+		}
+		return
+	}
+	*x = append(*x, field+": "+err.Error())
+}
+
+// Err возвращает nil, если ошибок нет, иначе ошибку base с перечнем ошибок полей
+func (x FieldErrors) Err(base merry.Error) error {
+	if len(x) == 0 {
+		return nil
+	}
+	return base.Here().WithValue(fieldErrorsKey{}, x).Append(strings.Join(x, "; "))
+}
+
+type fieldErrorsKey struct{}
@@ -0,0 +1,213 @@
+package comm
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Frame - кадр, принятый портом вне транзакций
+type Frame struct {
+	Time time.Time // момент приёма первого байта кадра
+	Port string
+	Data []byte
+	Err  error // ошибка выделения кадра, Data содержит отброшенные байты
+}
+
+// FramerFunc выделяет очередной кадр из начала принятых данных data.
+// idle == true, если после приёма data истёк таймаут окончания ответа Config.TimeoutEndResponse.
+// Возвращает количество обработанных байт advance и кадр frame, если он выделен.
+// Если advance == 0, приём данных продолжается
+type FramerFunc = func(data []byte, idle bool) (advance int, frame []byte, err error)
+
+// ErrIncompleteFrame - данные, из которых FramerFunc не выделил кадр до истечения таймаута окончания ответа
+var ErrIncompleteFrame = merry.New("неполный кадр")
+
+// FrameByIdle - FramerFunc, выделяющий кадры по паузе между ними
+func FrameByIdle(data []byte, idle bool) (int, []byte, error) {
+	if !idle {
+		return 0, nil, nil
+	}
+	return len(data), data, nil
+}
+
+// Listener принимает данные порта вне транзакций, выделяет из них кадры и рассылает подписчикам.
+// Listener удерживает порт, пока его не ожидает транзакция T с тем же WithLockPort,
+// транзакции выполняются между кадрами
+type Listener struct {
+	dropped uint64 // первое поле для выравнивания атомарного счётчика
+	t       T
+	framer  FramerFunc
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	subs    map[chan Frame]struct{}
+	err     error
+}
+
+// Listen запускает приём кадров вне транзакций. Порт должен быть задан WithLockPort.
+// Если framer == nil, используется FrameByIdle
+func (x T) Listen(framer FramerFunc) (*Listener, error) {
+	if len(x.port) == 0 {
+		return nil, merry.New("для приёма кадров вне транзакций необходимо задать порт WithLockPort")
+	}
+	if framer == nil {
+		framer = FrameByIdle
+	}
+	rr, err := newResponseReader(x.rw)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Listener{
+		t:      x,
+		framer: framer,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		subs:   make(map[chan Frame]struct{}),
+	}
+	go l.run(ctx, rr)
+	return l, nil
+}
+
+// Subscribe возвращает канал с буфером size, в который доставляются принятые кадры,
+// и функцию отмены подписки. Если буфер канала заполнен, кадр отбрасывается
+func (x *Listener) Subscribe(size int) (<-chan Frame, func()) {
+	c := make(chan Frame, size)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.subs == nil {
+		// приём завершён
+		close(c)
+		return c, func() {}
+	}
+	x.subs[c] = struct{}{}
+	return c, func() {
+		x.mu.Lock()
+		defer x.mu.Unlock()
+		if _, ok := x.subs[c]; ok {
+			delete(x.subs, c)
+			close(c)
+		}
+	}
+}
+
+// Dropped возвращает количество кадров, не доставленных подписчикам из-за заполнения буфера канала
+func (x *Listener) Dropped() uint64 {
+	return atomic.LoadUint64(&x.dropped)
+}
+
+// Close останавливает приём кадров и закрывает каналы подписчиков.
+// Возвращает ошибку чтения порта, если она прервала приём
+func (x *Listener) Close() error {
+	x.cancel()
+	<-x.done
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.err
+}
+
+func (x *Listener) run(ctx context.Context, rr responseReader) {
+	defer close(x.done)
+	defer x.closeSubscribers()
+	for {
+		holdCtx, err := x.t.lockPortIdle(ctx)
+		if err != nil {
+			return
+		}
+		err = x.receive(ctx, holdCtx, rr)
+		if errUnlock := x.t.unlockPort(); errUnlock != nil && err == nil {
+			err = errUnlock
+		}
+		if err != nil {
+			x.mu.Lock()
+			x.err = err
+			x.mu.Unlock()
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// receive принимает кадры, пока порт не потребуется транзакции, то есть до отмены holdCtx.
+// Начатый кадр принимается до конца
+func (x *Listener) receive(ctx, holdCtx context.Context, rr responseReader) error {
+	var (
+		data               []byte
+		firstReceiveMoment time.Time
+		lastReceiveMoment  time.Time
+	)
+	for {
+		done := ctx.Done()
+		if len(data) == 0 {
+			if holdCtx.Err() != nil {
+				return nil
+			}
+			done = holdCtx.Done()
+		}
+		b, err := rr.read(done, readPollInterval)
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 && len(data) == 0 {
+			continue
+		}
+		if len(b) > 0 {
+			if len(data) == 0 {
+				firstReceiveMoment = time.Now()
+			}
+			data = append(data, b...)
+			lastReceiveMoment = time.Now()
+		}
+		idle := len(b) == 0 && time.Since(lastReceiveMoment) >= x.t.cfg.TimeoutEndResponse
+		if !idle && ctx.Err() != nil {
+			return nil
+		}
+		for len(data) > 0 {
+			advance, frame, err := x.framer(data, idle)
+			if err != nil {
+				x.publish(Frame{Time: firstReceiveMoment, Data: data, Err: err})
+				data = nil
+				break
+			}
+			if advance <= 0 || advance > len(data) {
+				break
+			}
+			if frame != nil {
+				x.publish(Frame{Time: firstReceiveMoment, Data: append([]byte(nil), frame...)})
+			}
+			data = data[advance:]
+			firstReceiveMoment = lastReceiveMoment
+		}
+		if idle && len(data) > 0 {
+			x.publish(Frame{Time: firstReceiveMoment, Data: data, Err: ErrIncompleteFrame.Here()})
+			data = nil
+		}
+	}
+}
+
+func (x *Listener) publish(f Frame) {
+	f.Port = x.t.statsPort()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for c := range x.subs {
+		select {
+		case c <- f:
+		default:
+			atomic.AddUint64(&x.dropped, 1)
+		}
+	}
+}
+
+func (x *Listener) closeSubscribers() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for c := range x.subs {
+		close(c)
+	}
+	x.subs = nil
+}
@@ -0,0 +1,143 @@
+package comm_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/fpawel/comm"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	port := new(pushPort)
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 10 * time.Millisecond,
+	}).WithLockPort("TestListener")
+
+	if _, err := comm.New(port, comm.Config{}).Listen(nil); err == nil {
+		t.Error("error expected for port without WithLockPort")
+	}
+
+	l, err := cm.Listen(func(data []byte, idle bool) (int, []byte, error) {
+		// кадры фиксированной длины 3
+		if len(data) < 3 {
+			return 0, nil, nil
+		}
+		return 3, data[:3], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames, unsubscribe := l.Subscribe(10)
+	defer unsubscribe()
+
+	port.push(1, 2, 3, 4, 5, 6)
+	assertFrames(t, frames, []byte{1, 2, 3}, []byte{4, 5, 6})
+
+	for i := byte(0); i < 10; i++ {
+		resp, err := cm.GetResponse(nil, context.Background(), []byte{0xA0 + i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(resp, []byte{0xA0 + i}) {
+			t.Fatalf("unexpected response % X", resp)
+		}
+	}
+
+	port.push(7, 8)
+	select {
+	case f := <-frames:
+		if !bytes.Equal(f.Data, []byte{7, 8}) || f.Err == nil {
+			t.Errorf("incomplete frame expected: %+v", f)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no frame")
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-frames; ok {
+		t.Error("channel must be closed")
+	}
+}
+
+func TestListenerIdlePort(t *testing.T) {
+	const portName = "TestListenerIdlePort"
+	port := new(pushPort)
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 10 * time.Millisecond,
+	}).WithLockPort(portName)
+	l, err := cm.Listen(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	acquired := comm.PortQueueStats(portName).Acquired
+	time.Sleep(50 * time.Millisecond)
+	if st := comm.PortQueueStats(portName); st.Acquired != acquired || st.Busy {
+		t.Errorf("listener must not be counted in queue stats: %+v", st)
+	}
+	info := make(chan comm.Info, 1)
+	if _, err := cm.WithNotifyDispatcher(comm.NewDispatcher(func(i comm.Info) {
+		info <- i
+	}, 1, comm.OverflowBlock)).GetResponse(nil, context.Background(), []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if i := <-info; i.LockWait > 10*time.Millisecond {
+		t.Errorf("lock wait %v", i.LockWait)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if st := comm.PortQueueStats(portName); st.Acquired != acquired+1 {
+		t.Errorf("unexpected queue stats %+v", st)
+	}
+}
+
+func assertFrames(t *testing.T, c <-chan comm.Frame, frames ...[]byte) {
+	t.Helper()
+	for _, b := range frames {
+		select {
+		case f := <-c:
+			if f.Err != nil || !bytes.Equal(f.Data, b) {
+				t.Errorf("frame % X expected, got % X: %v", b, f.Data, f.Err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no frame % X", b)
+		}
+	}
+}
+
+// pushPort отвечает копией запроса и позволяет передавать данные вне транзакций
+type pushPort struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (x *pushPort) push(b ...byte) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.buf = append(x.buf, b...)
+}
+
+func (x *pushPort) Write(p []byte) (int, error) {
+	x.push(p...)
+	return len(p), nil
+}
+
+func (x *pushPort) Available() (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return len(x.buf), nil
+}
+
+func (x *pushPort) Read(p []byte) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n := copy(p, x.buf)
+	x.buf = x.buf[n:]
+	return n, nil
+}
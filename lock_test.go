@@ -0,0 +1,64 @@
+package comm
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"testing"
+	"time"
+)
+
+func TestLockPortContext(t *testing.T) {
+	x := T{port: "TestLockPortContext"}
+	if _, err := x.lockPort(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	t0 := time.Now()
+	if _, err := x.lockPort(ctx); !merry.Is(err, context.Canceled) {
+		t.Fatalf("cancel expected: %v", err)
+	}
+	if d := time.Since(t0); d > 50*time.Millisecond {
+		t.Errorf("lock of canceled context took %v", d)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if wait, err := x.lockPort(ctx); !merry.Is(err, context.DeadlineExceeded) || wait < 20*time.Millisecond {
+		t.Fatalf("deadline expected: %v, %v", wait, err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		if err := x.unlockPort(); err != nil {
+			t.Error(err)
+		}
+	}()
+	wait, err := x.lockPort(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait < 30*time.Millisecond {
+		t.Errorf("lock wait %v", wait)
+	}
+	if err := x.unlockPort(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnlockNotLockedPort(t *testing.T) {
+	if err := (T{port: "TestUnlockNotLockedPort"}).unlockPort(); err == nil {
+		t.Fatal("error expected")
+	}
+	x := T{port: "TestUnlockNotLockedPort2"}
+	if _, err := x.lockPort(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.unlockPort(); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.unlockPort(); err == nil {
+		t.Fatal("error expected")
+	}
+}
@@ -0,0 +1,60 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"github.com/fpawel/comm"
+	"testing"
+	"time"
+)
+
+func TestBroadcast(t *testing.T) {
+	var requests [][]byte
+	port := writerFunc(func(p []byte) (int, error) {
+		requests = append(requests, append([]byte(nil), p...))
+		return len(p), nil
+	})
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: time.Second,
+		MaxAttemptsRead:    3,
+	})
+	err := RequestWrite32{
+		Addr:      AddrBroadcast,
+		ProtoCmd:  0x10,
+		DeviceCmd: 5,
+		Format:    BCD,
+		Value:     1,
+	}.Send(nil, context.Background(), cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Broadcast(nil, context.Background(), cm, 6, []byte{0, 1, 0, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("2 requests expected, got %d", len(requests))
+	}
+	for _, r := range requests {
+		if r[0] != 0 {
+			t.Errorf("broadcast address expected: % X", r)
+		}
+	}
+	if !bytes.Equal(requests[1], Request{ProtoCmd: 6, Data: []byte{0, 1, 0, 2}}.Bytes()) {
+		t.Errorf("unexpected request % X", requests[1])
+	}
+}
+
+// writerFunc - порт, записывающий запросы функцией и никогда не отвечающий
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func (writerFunc) Read([]byte) (int, error) {
+	return 0, nil
+}
+
+func (writerFunc) Available() (int, error) {
+	return 0, nil
+}
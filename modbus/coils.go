@@ -0,0 +1,225 @@
+package modbus
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/internal"
+)
+
+const (
+	maxReadBitsCount  = 2000 // наибольшее количество значений в запросе функций 1 и 2
+	maxWriteBitsCount = 1968 // наибольшее количество флагов в запросе функции 15
+)
+
+// RequestRead1 - запрос считывания флагов (coils), функция 1
+type RequestRead1 struct {
+	Addr      Addr
+	FirstBit  Var
+	BitsCount uint16
+}
+
+// RequestRead2 - запрос считывания дискретных входов, функция 2
+type RequestRead2 struct {
+	Addr      Addr
+	FirstBit  Var
+	BitsCount uint16
+}
+
+// RequestWrite5 - запрос записи флага (coil), функция 5
+type RequestWrite5 struct {
+	Addr  Addr
+	Bit   Var
+	Value bool
+}
+
+// RequestWrite15 - запрос записи нескольких флагов (coils), функция 15
+type RequestWrite15 struct {
+	Addr     Addr
+	FirstBit Var
+	Values   []bool
+}
+
+func (x RequestRead1) Request() Request {
+	return x.readBits().Request()
+}
+
+// GetResponse возвращает ответ, проверив количество байт данных
+func (x RequestRead1) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) ([]byte, error) {
+	return x.readBits().GetResponse(log, ctx, cm)
+}
+
+func (x RequestRead1) readBits() readBits {
+	return readBits{Addr: x.Addr, ProtoCmd: 1, FirstBit: x.FirstBit, BitsCount: x.BitsCount}
+}
+
+func (x RequestRead2) Request() Request {
+	return x.readBits().Request()
+}
+
+// GetResponse возвращает ответ, проверив количество байт данных
+func (x RequestRead2) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) ([]byte, error) {
+	return x.readBits().GetResponse(log, ctx, cm)
+}
+
+func (x RequestRead2) readBits() readBits {
+	return readBits{Addr: x.Addr, ProtoCmd: 2, FirstBit: x.FirstBit, BitsCount: x.BitsCount}
+}
+
+// ReadCoils считывает count флагов (coils), начиная с first
+func ReadCoils(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, first Var, count int) ([]bool, error) {
+	return readBitsCount(log, ctx, cm, addr, 1, first, count)
+}
+
+// ReadDiscreteInputs считывает count дискретных входов, начиная с first
+func ReadDiscreteInputs(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, first Var, count int) ([]bool, error) {
+	return readBitsCount(log, ctx, cm, addr, 2, first, count)
+}
+
+func readBitsCount(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, cmd ProtoCmd, first Var, count int) ([]bool, error) {
+	if err := checkQuantity(count, maxReadBitsCount); err != nil {
+		return nil, merry.Appendf(err, "считывание модбас бит %d, %d", first, count)
+	}
+	return readBits{Addr: addr, ProtoCmd: cmd, FirstBit: first, BitsCount: uint16(count)}.read(log, ctx, cm)
+}
+
+func (x RequestWrite5) Request() Request {
+	r := Request{
+		Addr:     x.Addr,
+		ProtoCmd: 5,
+		Data:     []byte{byte(x.Bit >> 8), byte(x.Bit), 0, 0},
+	}
+	if x.Value {
+		r.Data[2] = 0xFF
+	}
+	return r
+}
+
+// GetResponse проверяет, что ответ повторяет адрес и значение флага
+func (x RequestWrite5) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) error {
+	log = internal.LogPrependSuffixKeys(log, LogKeyFirstBit, x.Bit)
+	req := x.Request()
+	response, err := req.GetResponse(log, ctx, cm)
+	if err == nil {
+		err = checkEcho(req.Bytes(), response)
+	}
+	return merry.Appendf(err, "запись флага %d=%v", x.Bit, x.Value)
+}
+
+// WriteCoil записывает значение флага (coil)
+func WriteCoil(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, bit Var, value bool) error {
+	return RequestWrite5{Addr: addr, Bit: bit, Value: value}.GetResponse(log, ctx, cm)
+}
+
+func (x RequestWrite15) Request() Request {
+	packed := packBits(x.Values)
+	r := Request{
+		Addr:     x.Addr,
+		ProtoCmd: 15,
+		Data: []byte{
+			byte(x.FirstBit >> 8),
+			byte(x.FirstBit),
+			byte(len(x.Values) >> 8),
+			byte(len(x.Values)),
+			byte(len(packed)),
+		},
+	}
+	r.Data = append(r.Data, packed...)
+	return r
+}
+
+// GetResponse проверяет, что ответ повторяет адрес первого флага и количество флагов
+func (x RequestWrite15) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) error {
+	wrapErr := func(err error) error {
+		return merry.Appendf(err, "запись %d флагов, начиная с %d", len(x.Values), x.FirstBit)
+	}
+	if err := checkQuantity(len(x.Values), maxWriteBitsCount); err != nil {
+		return wrapErr(err)
+	}
+	log = internal.LogPrependSuffixKeys(log,
+		LogKeyBitsCount, len(x.Values),
+		LogKeyFirstBit, x.FirstBit,
+	)
+	req := x.Request()
+	response, err := req.GetResponse(log, ctx, cm)
+	if err == nil {
+		err = checkEcho(req.Bytes(), response)
+	}
+	return wrapErr(err)
+}
+
+// WriteCoils записывает значения флагов (coils), начиная с first
+func WriteCoils(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, first Var, values []bool) error {
+	return RequestWrite15{Addr: addr, FirstBit: first, Values: values}.GetResponse(log, ctx, cm)
+}
+
+// readBits - запрос считывания дискретных значений функцией 1 или 2
+type readBits struct {
+	Addr      Addr
+	ProtoCmd  ProtoCmd
+	FirstBit  Var
+	BitsCount uint16
+}
+
+func (x readBits) Request() Request {
+	return Request{
+		Addr:     x.Addr,
+		ProtoCmd: x.ProtoCmd,
+		Data: []byte{
+			byte(x.FirstBit >> 8),
+			byte(x.FirstBit),
+			byte(x.BitsCount >> 8),
+			byte(x.BitsCount),
+		},
+	}
+}
+
+func (x readBits) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) ([]byte, error) {
+	if err := checkQuantity(int(x.BitsCount), maxReadBitsCount); err != nil {
+		return nil, merry.Appendf(err, "считывание модбас бит %d, %d", x.FirstBit, x.BitsCount)
+	}
+	log = internal.LogPrependSuffixKeys(log,
+		LogKeyBitsCount, x.BitsCount,
+		LogKeyFirstBit, x.FirstBit,
+	)
+	cm = cm.WithAppendParse(func(request, response []byte) error {
+		bytesCount := (int(x.BitsCount) + 7) / 8
+		if len(response) != bytesCount+5 {
+			return merry.Errorf("ожидалось %d байт ответа, получено %d", bytesCount+5, len(response))
+		}
+		if int(response[2]) != bytesCount {
+			return Err.Here().Appendf("ожидалось %d байт данных, в ответе указано %d", bytesCount, response[2])
+		}
+		return nil
+	})
+	b, err := x.Request().GetResponse(log, ctx, cm)
+	return b, merry.Appendf(err, "считывание модбас бит %d, %d", x.FirstBit, x.BitsCount)
+}
+
+func (x readBits) read(log comm.Logger, ctx context.Context, cm comm.T) ([]bool, error) {
+	response, err := x.GetResponse(log, ctx, cm)
+	if err != nil {
+		return nil, err
+	}
+	return unpackBits(response[3:len(response)-2], int(x.BitsCount)), nil
+}
+
+// packBits упаковывает значения по 8 в байт, начиная с младшего бита
+func packBits(values []bool) []byte {
+	b := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			b[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return b
+}
+
+// unpackBits распаковывает count значений, упакованных packBits
+func unpackBits(b []byte, count int) []bool {
+	values := make([]bool, count)
+	for i := range values {
+		values[i] = b[i/8]&(1<<uint(i%8)) != 0
+	}
+	return values
+}
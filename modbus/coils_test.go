@@ -0,0 +1,119 @@
+package modbus
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"reflect"
+	"testing"
+)
+
+func TestCoils(t *testing.T) {
+	dev := newMockDevice(1)
+	for i := Var(0); i < 10; i++ {
+		dev.coils[i] = false
+		dev.discrete[100+i] = i%3 == 0
+	}
+	cm := dev.comm()
+	ctx := context.Background()
+
+	if err := WriteCoil(nil, ctx, cm, 1, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	values := []bool{true, false, true, true, false, false, true, true, true}
+	if err := WriteCoils(nil, ctx, cm, 1, 1, values); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadCoils(nil, ctx, cm, 1, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]bool{false}, values...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got, err = ReadDiscreteInputs(nil, ctx, cm, 1, 100, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if v != (i%3 == 0) {
+			t.Errorf("discrete input %d: %v", i, v)
+		}
+	}
+
+	if _, err := ReadCoils(nil, ctx, cm, 1, 5, 10); !merry.Is(err, ErrException) {
+		t.Errorf("exception expected: %v", err)
+	}
+}
+
+func TestCoilsQuantity(t *testing.T) {
+	cm := comport.NewMock(func(req []byte) []byte {
+		t.Errorf("request must not be sent: % X", req)
+		return nil
+	})
+	ctx := context.Background()
+	for _, count := range []int{0, 2001, 0x10001} {
+		if _, err := ReadCoils(nil, ctx, cm, 1, 0, count); err == nil {
+			t.Errorf("read1 %d: error expected", count)
+		}
+		if _, err := ReadDiscreteInputs(nil, ctx, cm, 1, 0, count); err == nil {
+			t.Errorf("read2 %d: error expected", count)
+		}
+	}
+	if _, err := (RequestRead1{Addr: 1, BitsCount: 2001}).GetResponse(nil, ctx, cm); err == nil {
+		t.Error("read1 2001: error expected")
+	}
+	for _, count := range []int{0, 1969, 2041} {
+		if err := WriteCoils(nil, ctx, cm, 1, 0, make([]bool, count)); err == nil {
+			t.Errorf("write15 %d: error expected", count)
+		}
+	}
+}
+
+func TestCoilsEncoding(t *testing.T) {
+	for _, x := range []struct {
+		r    Request
+		data []byte
+	}{
+		{RequestWrite5{Bit: 0xAC, Value: true}.Request(), []byte{0, 0xAC, 0xFF, 0}},
+		{RequestWrite5{Bit: 0xAC}.Request(), []byte{0, 0xAC, 0, 0}},
+		{
+			RequestWrite15{FirstBit: 19, Values: []bool{
+				true, false, true, true, false, false, true, true,
+				true, false,
+			}}.Request(),
+			[]byte{0, 19, 0, 10, 2, 0xCD, 0x01},
+		},
+	} {
+		if !reflect.DeepEqual(x.r.Data, x.data) {
+			t.Errorf("got % X, want % X", x.r.Data, x.data)
+		}
+	}
+}
+
+func TestCoilsValidateResponse(t *testing.T) {
+	ctx := context.Background()
+	testInvalidResponses(t, []invalidResponse{
+		{
+			"write5 echo",
+			Request{Addr: 1, ProtoCmd: 5, Data: []byte{0, 3, 0, 0}}.Bytes(),
+			func(cm comm.T) error { return WriteCoil(nil, ctx, cm, 1, 3, true) },
+		},
+		{
+			"write15 echo",
+			Request{Addr: 1, ProtoCmd: 15, Data: []byte{0, 3, 0, 2}}.Bytes(),
+			func(cm comm.T) error { return WriteCoils(nil, ctx, cm, 1, 3, []bool{true, true, true}) },
+		},
+		{
+			"read1 byte count",
+			Request{Addr: 1, ProtoCmd: 1, Data: []byte{2, 0xFF}}.Bytes(),
+			func(cm comm.T) error {
+				_, err := ReadCoils(nil, ctx, cm, 1, 0, 8)
+				return err
+			},
+		},
+	})
+}
@@ -0,0 +1,207 @@
+package modbus
+
+import (
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"testing"
+)
+
+// mockDevice - модбас устройство с адресом addr для тестов
+type mockDevice struct {
+	addr            Addr
+	holding, input  map[Var]uint16
+	coils, discrete map[Var]bool
+	// объекты идентификации устройства и наибольшее количество объектов в ответе
+	objects           map[DeviceObjectID]string
+	maxObjectsInFrame int
+	requests          int
+}
+
+func newMockDevice(addr Addr) *mockDevice {
+	return &mockDevice{
+		addr:     addr,
+		holding:  make(map[Var]uint16),
+		input:    make(map[Var]uint16),
+		coils:    make(map[Var]bool),
+		discrete: make(map[Var]bool),
+	}
+}
+
+func (x *mockDevice) comm() comm.T {
+	return comport.NewMock(x.response)
+}
+
+func (x *mockDevice) response(req []byte) []byte {
+	if len(req) < 4 || Addr(req[0]) != x.addr {
+		return nil
+	}
+	if h, l := CRC16(req); h != 0 || l != 0 {
+		return nil
+	}
+	x.requests++
+	cmd := ProtoCmd(req[1])
+	data, exception := x.handle(cmd, req[2:len(req)-2])
+	if exception != 0 {
+		return Request{Addr: x.addr, ProtoCmd: cmd | 0x80, Data: []byte{exception}}.Bytes()
+	}
+	return Request{Addr: x.addr, ProtoCmd: cmd, Data: data}.Bytes()
+}
+
+// handle возвращает данные ответа на запрос функции cmd либо код ошибки
+func (x *mockDevice) handle(cmd ProtoCmd, data []byte) ([]byte, byte) {
+	switch cmd {
+	case 1:
+		return readMockBits(x.coils, data)
+	case 2:
+		return readMockBits(x.discrete, data)
+	case 5:
+		if len(data) != 4 || data[3] != 0 || data[2] != 0 && data[2] != 0xFF {
+			return nil, 3
+		}
+		x.coils[Var(uint16(data[0])<<8|uint16(data[1]))] = data[2] == 0xFF
+		return data, 0
+	case 15:
+		if len(data) < 5 {
+			return nil, 3
+		}
+		first, count := Var(uint16(data[0])<<8|uint16(data[1])), int(data[2])<<8|int(data[3])
+		if int(data[4]) != (count+7)/8 || len(data) != 5+int(data[4]) {
+			return nil, 3
+		}
+		for i, v := range unpackBits(data[5:], count) {
+			x.coils[first+Var(i)] = v
+		}
+		return data[:4], 0
+	case 6:
+		if len(data) != 4 {
+			return nil, 3
+		}
+		x.holding[Var(uint16(data[0])<<8|uint16(data[1]))] = uint16(data[2])<<8 | uint16(data[3])
+		return data, 0
+	case 16:
+		if len(data) < 5 {
+			return nil, 3
+		}
+		first, count := Var(uint16(data[0])<<8|uint16(data[1])), int(data[2])<<8|int(data[3])
+		if int(data[4]) != count*2 || len(data) != 5+count*2 {
+			return nil, 3
+		}
+		for i := 0; i < count; i++ {
+			x.holding[first+Var(i)] = uint16(data[5+i*2])<<8 | uint16(data[6+i*2])
+		}
+		return data[:4], 0
+	case 22:
+		if len(data) != 6 {
+			return nil, 3
+		}
+		reg := Var(uint16(data[0])<<8 | uint16(data[1]))
+		and, or := uint16(data[2])<<8|uint16(data[3]), uint16(data[4])<<8|uint16(data[5])
+		v, ok := x.holding[reg]
+		if !ok {
+			return nil, 2
+		}
+		x.holding[reg] = v&and | or&^and
+		return data, 0
+	case 23:
+		if len(data) < 9 {
+			return nil, 3
+		}
+		if _, exception := x.handle(16, data[4:]); exception != 0 {
+			return nil, exception
+		}
+		return readMockRegisters(x.holding, data[:4])
+	case 43:
+		return x.readIdentification(data)
+	case 3:
+		return readMockRegisters(x.holding, data)
+	case 4:
+		return readMockRegisters(x.input, data)
+	default:
+		return nil, 1
+	}
+}
+
+func readMockRegisters(regs map[Var]uint16, data []byte) ([]byte, byte) {
+	if len(data) != 4 {
+		return nil, 3
+	}
+	first, count := Var(uint16(data[0])<<8|uint16(data[1])), int(data[2])<<8|int(data[3])
+	r := []byte{byte(count * 2)}
+	for i := 0; i < count; i++ {
+		v, ok := regs[first+Var(i)]
+		if !ok {
+			return nil, 2
+		}
+		r = append(r, byte(v>>8), byte(v))
+	}
+	return r, 0
+}
+
+func readMockBits(bits map[Var]bool, data []byte) ([]byte, byte) {
+	if len(data) != 4 {
+		return nil, 3
+	}
+	first, count := Var(uint16(data[0])<<8|uint16(data[1])), int(data[2])<<8|int(data[3])
+	values := make([]bool, count)
+	for i := range values {
+		v, ok := bits[first+Var(i)]
+		if !ok {
+			return nil, 2
+		}
+		values[i] = v
+	}
+	b := packBits(values)
+	return append([]byte{byte(len(b))}, b...), 0
+}
+
+func (x *mockDevice) readIdentification(data []byte) ([]byte, byte) {
+	if len(data) != 3 || data[0] != meiReadDeviceIdentification {
+		return nil, 3
+	}
+	code, first := DeviceIDCode(data[1]), DeviceObjectID(data[2])
+	last := map[DeviceIDCode]DeviceObjectID{
+		DeviceIDBasic:      ObjectMajorMinorRevision,
+		DeviceIDRegular:    0x7F,
+		DeviceIDExtended:   0xFF,
+		DeviceIDIndividual: first,
+	}[code]
+	if _, ok := x.objects[first]; !ok || last < first {
+		return nil, 2
+	}
+	r := []byte{meiReadDeviceIdentification, byte(code), 0x83, 0, 0, 0}
+	for id := int(first); id <= int(last); id++ {
+		s, ok := x.objects[DeviceObjectID(id)]
+		if !ok {
+			continue
+		}
+		if x.maxObjectsInFrame > 0 && int(r[5]) == x.maxObjectsInFrame {
+			r[3], r[4] = 0xFF, byte(id)
+			break
+		}
+		r = append(r, byte(id), byte(len(s)))
+		r = append(r, s...)
+		r[5]++
+	}
+	return r, 0
+}
+
+// invalidResponse - ответ response, который запрос f должен отвергнуть с ошибкой Err
+type invalidResponse struct {
+	name     string
+	response []byte
+	f        func(cm comm.T) error
+}
+
+func testInvalidResponses(t *testing.T, xs []invalidResponse) {
+	t.Helper()
+	for _, x := range xs {
+		response := x.response
+		cm := comport.NewMock(func([]byte) []byte {
+			return response
+		})
+		if err := x.f(cm); !merry.Is(err, Err) {
+			t.Errorf("%s: modbus error expected: %v", x.name, err)
+		}
+	}
+}
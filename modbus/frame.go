@@ -0,0 +1,58 @@
+package modbus
+
+// FrameComplete определяет по коду функции ответа, что ответ модбас RTU получен полностью.
+// Для функций с неизвестной длиной ответа возвращает false, и тогда окончание ответа
+// определяется таймаутом окончания ответа comm.Config.TimeoutEndResponse.
+// Request.GetResponse использует FrameComplete, если функция не задана comm.T.WithFrameComplete
+func FrameComplete(_, partial []byte) (bool, error) {
+	n := FrameLen(partial)
+	return n > 0 && len(partial) >= n, nil
+}
+
+// FrameLen возвращает ожидаемую длину ответа модбас RTU, включая CRC16, по его начальной части.
+// Если длина ещё не может быть определена или зависит от содержимого ответа, возвращает 0
+func FrameLen(partial []byte) int {
+	if len(partial) < 2 {
+		return 0
+	}
+	cmd := partial[1]
+	if cmd&0x80 != 0 {
+		// адрес, код функции, код ошибки, CRC16
+		return 5
+	}
+	switch cmd {
+	case 1, 2, 3, 4, 23:
+		// адрес, код функции, количество байт, данные, CRC16
+		if len(partial) < 3 {
+			return 0
+		}
+		return 5 + int(partial[2])
+	case 5, 6, 15, 16:
+		// адрес, код функции, адрес регистра, значение или количество, CRC16
+		return 8
+	case 22:
+		// адрес, код функции, адрес регистра, маска AND, маска OR, CRC16
+		return 10
+	case 43:
+		return deviceIdentificationFrameLen(partial)
+	default:
+		return 0
+	}
+}
+
+// deviceIdentificationFrameLen возвращает длину ответа функции 43 / MEI 14: адрес, код функции, MEI,
+// код чтения, уровень соответствия, признак продолжения, следующий объект, количество объектов,
+// объекты (идентификатор, длина, значение), CRC16
+func deviceIdentificationFrameLen(partial []byte) int {
+	if len(partial) < 8 || partial[2] != meiReadDeviceIdentification {
+		return 0
+	}
+	n := 8
+	for i := 0; i < int(partial[7]); i++ {
+		if len(partial) < n+2 {
+			return 0
+		}
+		n += 2 + int(partial[n+1])
+	}
+	return n + 2
+}
@@ -0,0 +1,114 @@
+package modbus
+
+import (
+	"context"
+	"github.com/fpawel/comm"
+	"testing"
+	"time"
+)
+
+func TestFrameLen(t *testing.T) {
+	for _, x := range []struct {
+		partial []byte
+		n       int
+	}{
+		{nil, 0},
+		{[]byte{1}, 0},
+		{[]byte{1, 3}, 0},
+		{[]byte{1, 3, 4}, 9},
+		{[]byte{1, 4, 2, 0}, 7},
+		{[]byte{1, 0x83}, 5},
+		{[]byte{1, 6}, 8},
+		{[]byte{1, 16, 0, 32}, 8},
+		{[]byte{1, 22}, 10},
+		{[]byte{1, 43, 14}, 0},
+		{[]byte{1, 43, 14, 1, 1, 0, 0, 2}, 0},
+		{[]byte{1, 43, 14, 1, 1, 0, 0, 2, 0, 3, 'A', 'B', 'C'}, 0},
+		{[]byte{1, 43, 14, 1, 1, 0, 0, 2, 0, 3, 'A', 'B', 'C', 1, 1}, 18},
+		{[]byte{1, 43, 13}, 0},
+	} {
+		if n := FrameLen(x.partial); n != x.n {
+			t.Errorf("% X: %d, expected %d", x.partial, n, x.n)
+		}
+	}
+}
+
+func TestRead3FrameComplete(t *testing.T) {
+	port := &chunkedPort{}
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: time.Second,
+	})
+	req := RequestRead3{Addr: 1, FirstRegister: 0, RegistersCount: 2}
+	response := Request{Addr: 1, ProtoCmd: 3, Data: []byte{4, 0x41, 0x20, 0, 0}}.Bytes()
+	// ответ приходит двумя частями с паузой внутри кадра
+	port.chunks = [][]byte{response[:4], response[4:]}
+
+	t0 := time.Now()
+	b, err := req.GetResponse(nil, context.Background(), cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(t0); d > 500*time.Millisecond {
+		t.Errorf("response duration %v", d)
+	}
+	if string(b) != string(response) {
+		t.Errorf("% X, expected % X", b, response)
+	}
+}
+
+func TestRequestKeepsFrameComplete(t *testing.T) {
+	port := &chunkedPort{}
+	calls := 0
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 50 * time.Millisecond,
+	}).WithFrameComplete(func(request, partial []byte) (bool, error) {
+		calls++
+		return false, nil
+	})
+	req := RequestRead3{Addr: 1, FirstRegister: 0, RegistersCount: 2}
+	response := Request{Addr: 1, ProtoCmd: 3, Data: []byte{4, 0x41, 0x20, 0, 0}}.Bytes()
+	port.chunks = [][]byte{response}
+
+	t0 := time.Now()
+	if _, err := req.GetResponse(nil, context.Background(), cm); err != nil {
+		t.Fatal(err)
+	}
+	if calls == 0 {
+		t.Error("frame complete predicate was replaced")
+	}
+	// ответ завершается только по таймауту окончания ответа
+	if d := time.Since(t0); d < 50*time.Millisecond {
+		t.Errorf("response duration %v", d)
+	}
+}
+
+// chunkedPort выдаёт ответ частями chunks, по одной части на каждые 10 мс
+type chunkedPort struct {
+	chunks [][]byte
+	t      time.Time
+	buf    []byte
+}
+
+func (x *chunkedPort) Write(p []byte) (int, error) {
+	x.t = time.Now()
+	return len(p), nil
+}
+
+func (x *chunkedPort) Available() (int, error) {
+	return x.Read(nil)
+}
+
+func (x *chunkedPort) Read(p []byte) (int, error) {
+	if len(x.buf) == 0 && len(x.chunks) > 0 && !x.t.IsZero() && time.Since(x.t) > 10*time.Millisecond {
+		x.buf, x.chunks = x.chunks[0], x.chunks[1:]
+		x.t = time.Now()
+	}
+	if len(p) == 0 {
+		return len(x.buf), nil
+	}
+	n := copy(p, x.buf)
+	x.buf = x.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,147 @@
+package modbus
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"strconv"
+)
+
+// DeviceIDCode - код чтения идентификации устройства, функция 43 / MEI 14
+type DeviceIDCode byte
+
+const (
+	DeviceIDBasic      DeviceIDCode = 1 // обязательные объекты 0..2
+	DeviceIDRegular    DeviceIDCode = 2 // необязательные объекты 3..0x7F
+	DeviceIDExtended   DeviceIDCode = 3 // объекты производителя 0x80..0xFF
+	DeviceIDIndividual DeviceIDCode = 4 // один объект
+)
+
+// DeviceObjectID - идентификатор объекта идентификации устройства
+type DeviceObjectID byte
+
+const (
+	ObjectVendorName          DeviceObjectID = 0
+	ObjectProductCode         DeviceObjectID = 1
+	ObjectMajorMinorRevision  DeviceObjectID = 2
+	ObjectVendorURL           DeviceObjectID = 3
+	ObjectProductName         DeviceObjectID = 4
+	ObjectModelName           DeviceObjectID = 5
+	ObjectUserApplicationName DeviceObjectID = 6
+)
+
+func (x DeviceObjectID) String() string {
+	switch x {
+	case ObjectVendorName:
+		return "VendorName"
+	case ObjectProductCode:
+		return "ProductCode"
+	case ObjectMajorMinorRevision:
+		return "MajorMinorRevision"
+	case ObjectVendorURL:
+		return "VendorUrl"
+	case ObjectProductName:
+		return "ProductName"
+	case ObjectModelName:
+		return "ModelName"
+	case ObjectUserApplicationName:
+		return "UserApplicationName"
+	default:
+		return "0x" + strconv.FormatUint(uint64(x), 16)
+	}
+}
+
+// RequestReadDeviceIdentification - запрос идентификации устройства, функция 43 / MEI 14.
+// ObjectID - идентификатор первого считываемого объекта
+type RequestReadDeviceIdentification struct {
+	Addr     Addr
+	Code     DeviceIDCode
+	ObjectID DeviceObjectID
+}
+
+// DeviceIdentification - ответ на запрос идентификации устройства
+type DeviceIdentification struct {
+	Code         DeviceIDCode
+	Conformity   byte                      // уровень соответствия устройства
+	MoreFollows  bool                      // не все объекты поместились в ответ
+	NextObjectID DeviceObjectID            // идентификатор объекта для следующего запроса при MoreFollows
+	Objects      map[DeviceObjectID]string // объекты ответа
+}
+
+func (x RequestReadDeviceIdentification) Request() Request {
+	return Request{
+		Addr:     x.Addr,
+		ProtoCmd: 43,
+		Data:     []byte{meiReadDeviceIdentification, byte(x.Code), byte(x.ObjectID)},
+	}
+}
+
+// GetResponse возвращает объекты идентификации одного ответа, проверив его формат
+func (x RequestReadDeviceIdentification) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) (DeviceIdentification, error) {
+	var r DeviceIdentification
+	cm = cm.WithAppendParse(func(request, response []byte) (err error) {
+		r, err = x.parseResponse(response)
+		return
+	})
+	_, err := x.Request().GetResponse(log, ctx, cm)
+	return r, merry.Appendf(err, "идентификация устройства, код %d, объект %s", x.Code, x.ObjectID)
+}
+
+// ReadDeviceIdentification считывает объекты идентификации устройства с кодом чтения code,
+// начиная с ObjectVendorName и выполняя повторные запросы, пока устройство сообщает о продолжении.
+// Для чтения отдельного объекта используется RequestReadDeviceIdentification с кодом DeviceIDIndividual
+func ReadDeviceIdentification(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, code DeviceIDCode) (map[DeviceObjectID]string, error) {
+	if code < DeviceIDBasic || code > DeviceIDExtended {
+		return nil, merry.Errorf("идентификация устройства: неверный код чтения %d", code)
+	}
+	objects := make(map[DeviceObjectID]string)
+	req := RequestReadDeviceIdentification{Addr: addr, Code: code, ObjectID: ObjectVendorName}
+	requested := make(map[DeviceObjectID]struct{})
+	for {
+		requested[req.ObjectID] = struct{}{}
+		r, err := req.GetResponse(log, ctx, cm)
+		if err != nil {
+			return objects, err
+		}
+		for id, s := range r.Objects {
+			objects[id] = s
+		}
+		if !r.MoreFollows {
+			return objects, nil
+		}
+		if _, ok := requested[r.NextObjectID]; ok {
+			return objects, Err.Here().Appendf("идентификация устройства: повторный запрос объекта %s", r.NextObjectID)
+		}
+		req.ObjectID = r.NextObjectID
+	}
+}
+
+func (x RequestReadDeviceIdentification) parseResponse(response []byte) (DeviceIdentification, error) {
+	var r DeviceIdentification
+	n := deviceIdentificationFrameLen(response)
+	if n == 0 || n != len(response) {
+		return r, Err.Here().Appendf("неверная длина ответа %d", len(response))
+	}
+	if DeviceIDCode(response[3]) != x.Code {
+		return r, Err.Here().Appendf("код чтения ответа %d не совпадает с кодом запроса %d", response[3], x.Code)
+	}
+	r = DeviceIdentification{
+		Code:         x.Code,
+		Conformity:   response[4],
+		MoreFollows:  response[5] == 0xFF,
+		NextObjectID: DeviceObjectID(response[6]),
+		Objects:      make(map[DeviceObjectID]string),
+	}
+	if response[5] != 0 && response[5] != 0xFF {
+		return r, Err.Here().Appendf("неверный признак продолжения %02X", response[5])
+	}
+	b := response[8 : len(response)-2]
+	for len(b) > 0 {
+		id, size := DeviceObjectID(b[0]), int(b[1])
+		r.Objects[id] = string(b[2 : 2+size])
+		b = b[2+size:]
+	}
+	return r, nil
+}
+
+const meiReadDeviceIdentification = 0x0E
@@ -0,0 +1,61 @@
+package modbus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestReadDeviceIdentification(t *testing.T) {
+	dev := newMockDevice(1)
+	dev.objects = map[DeviceObjectID]string{
+		ObjectVendorName:         "Vendor",
+		ObjectProductCode:        "P-100",
+		ObjectMajorMinorRevision: "1.2",
+		ObjectVendorURL:          "http://vendor",
+		ObjectModelName:          "Model",
+		0x80:                     "private",
+	}
+	dev.maxObjectsInFrame = 2
+	cm := dev.comm()
+	ctx := context.Background()
+
+	for _, x := range []struct {
+		code     DeviceIDCode
+		ids      []DeviceObjectID
+		requests int
+	}{
+		{DeviceIDBasic, []DeviceObjectID{0, 1, 2}, 2},
+		{DeviceIDRegular, []DeviceObjectID{0, 1, 2, 3, 5}, 3},
+		{DeviceIDExtended, []DeviceObjectID{0, 1, 2, 3, 5, 0x80}, 3},
+	} {
+		dev.requests = 0
+		got, err := ReadDeviceIdentification(nil, ctx, cm, 1, x.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make(map[DeviceObjectID]string)
+		for _, id := range x.ids {
+			want[id] = dev.objects[id]
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("code %d: got %v, want %v", x.code, got, want)
+		}
+		if dev.requests != x.requests {
+			t.Errorf("code %d: %d requests, want %d", x.code, dev.requests, x.requests)
+		}
+	}
+
+	r, err := RequestReadDeviceIdentification{Addr: 1, Code: DeviceIDIndividual, ObjectID: ObjectModelName}.
+		GetResponse(nil, ctx, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MoreFollows || len(r.Objects) != 1 || r.Objects[ObjectModelName] != "Model" {
+		t.Errorf("unexpected response %+v", r)
+	}
+
+	if _, err := ReadDeviceIdentification(nil, ctx, cm, 1, DeviceIDIndividual); err == nil {
+		t.Error("error expected for individual access")
+	}
+}
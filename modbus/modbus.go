@@ -6,11 +6,15 @@ import (
 	"github.com/fpawel/comm"
 	"github.com/fpawel/comm/internal"
 	"github.com/powerman/structlog"
+	"strconv"
 )
 
 type ProtoCmd byte
 type Addr byte
 
+// AddrBroadcast - адрес широковещательного запроса, на который устройства не отвечают
+const AddrBroadcast Addr = 0
+
 type Var uint16
 
 type Request struct {
@@ -23,7 +27,8 @@ type DevCmd uint16
 
 var (
 	Err            = merry.New("не верный ответ модбас").WithCause(comm.Err)
-	ErrCRC16       = merry.New("несовпадение CRC16 в ответе модбас").WithCause(Err)
+	ErrCRC16       = comm.WithErrorKind(merry.New("несовпадение CRC16 в ответе модбас").WithCause(Err), comm.ErrorKindCRC)
+	ErrException   = comm.WithErrorKind(merry.New("модбас устройство вернуло код ошибки").WithCause(Err), comm.ErrorKindException)
 	ErrFloatFormat = merry.New("не верный формат числа с плавающей точкой в ответе модбас")
 )
 
@@ -35,6 +40,8 @@ const (
 	LogKeyFirstReg     = "модбас_регистр"
 	LogKeyDeviceCmd    = "модбас_запись32"
 	LogKeyDeviceCmdArg = "модбас_аргумент"
+	LogKeyBitsCount    = "модбас_число_бит"
+	LogKeyFirstBit     = "модбас_бит"
 )
 
 func SetLogKeysFormat() {
@@ -64,11 +71,34 @@ func (x Request) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) ([
 			return err
 		}
 		return nil
-	})
+	}).WithStatsDevice(strconv.Itoa(int(x.Addr)), strconv.Itoa(int(x.ProtoCmd)))
+	if cm.FrameComplete() == nil {
+		cm = cm.WithFrameComplete(FrameComplete)
+	}
 	b, err := cm.GetResponse(log, ctx, x.Bytes())
 	return b, merry.Appendf(err, "модбас[адрес %d команда %d]", x.Addr, x.ProtoCmd)
 }
 
+// Send отправляет запрос, не ожидая ответа, например широковещательный запрос с адресом AddrBroadcast
+func (x Request) Send(log comm.Logger, ctx context.Context, cm comm.T) error {
+	log = internal.LogPrependSuffixKeys(log,
+		LogKeyAddr, x.Addr,
+		LogKeyCmd, x.ProtoCmd,
+		LogKeyData, x.Data)
+	cm = cm.WithStatsDevice(strconv.Itoa(int(x.Addr)), strconv.Itoa(int(x.ProtoCmd)))
+	err := cm.Send(log, ctx, x.Bytes())
+	return merry.Appendf(err, "модбас[адрес %d команда %d]", x.Addr, x.ProtoCmd)
+}
+
+// Broadcast отправляет широковещательный запрос с адресом AddrBroadcast
+func Broadcast(log comm.Logger, ctx context.Context, cm comm.T, protoCmd ProtoCmd, data []byte) error {
+	return Request{
+		Addr:     AddrBroadcast,
+		ProtoCmd: protoCmd,
+		Data:     data,
+	}.Send(log, ctx, cm)
+}
+
 func (x *Request) ParseBCDValue(b []byte) (v float64, err error) {
 	if err = x.checkResponse(b); err != nil {
 		return
@@ -103,7 +133,8 @@ func (x Request) checkResponse(response []byte) error {
 	}
 
 	if len(response) == 5 && byte(x.ProtoCmd)|0x80 == response[1] {
-		return Err.Here().Appendf("код ошибки модбас %d", response[2])
+		return ErrException.Here().WithValue(keyExceptionCode, response[2]).
+			Appendf("код ошибки модбас %d", response[2])
 	}
 	if response[1] != byte(x.ProtoCmd) {
 		return Err.Here().Append("несовпадение кодов команд модбас запроса и ответа")
@@ -111,3 +142,42 @@ func (x Request) checkResponse(response []byte) error {
 
 	return nil
 }
+
+// checkEcho проверяет, что ответ на запрос записи повторяет начальный адрес и значение либо количество из запроса
+func checkEcho(request, response []byte) error {
+	if len(response) < 6 {
+		return Err.Here().Appendf("ожидалось не менее 6 байт ответа, получено %d", len(response))
+	}
+	for i := 2; i < 6; i++ {
+		if request[i] != response[i] {
+			return merry.Appendf(Err.Here(),
+				"ошибка формата: запрос[2:6]==[% X] != ответ[2:6]==[% X]", request[2:6], response[2:6])
+		}
+	}
+	return nil
+}
+
+// checkQuantity проверяет, что количество count значений в запросе находится в пределах от 1 до max,
+// установленных протоколом модбас
+func checkQuantity(count, max int) error {
+	if count < 1 || count > max {
+		return merry.Errorf("количество значений в запросе должно быть от 1 до %d: %d", max, count)
+	}
+	return nil
+}
+
+// ExceptionCode возвращает код ошибки модбас из ошибки ErrException
+func ExceptionCode(err error) (byte, bool) {
+	code, ok := merry.Value(err, keyExceptionCode).(byte)
+	return code, ok
+}
+
+// RetryOn - comm.RetryClassifier, повторяющий запрос после таймаутов и ошибок ответа,
+// в том числе ErrCRC16, но не после кода ошибки ErrException
+func RetryOn(err error) bool {
+	return comm.IsRetryable(err) && !merry.Is(err, ErrException)
+}
+
+type exceptionCodeKey struct{}
+
+var keyExceptionCode exceptionCodeKey
@@ -0,0 +1,46 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/fpawel/comm"
+)
+
+// RequestRead4 - запрос считывания входных регистров, функция 4
+type RequestRead4 struct {
+	Addr           Addr
+	FirstRegister  Var
+	RegistersCount uint16
+}
+
+func (x RequestRead4) Request() Request {
+	return x.readRegisters().Request()
+}
+
+func (x RequestRead4) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) ([]byte, error) {
+	return x.readRegisters().GetResponse(log, ctx, cm)
+}
+
+func (x RequestRead4) readRegisters() readRegisters {
+	return readRegisters{
+		Addr:           x.Addr,
+		ProtoCmd:       4,
+		FirstRegister:  x.FirstRegister,
+		RegistersCount: x.RegistersCount,
+	}
+}
+
+// Read4Values считывает count значений из входных регистров, начиная с var4
+func Read4Values(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, var4 Var, count int, format FloatBitsFormat) ([]float64, error) {
+	return readValues(log, ctx, cm, 4, addr, var4, count, format)
+}
+
+// Read4Value считывает значение из входных регистров var4, var4+1
+func Read4Value(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, var4 Var, format FloatBitsFormat) (float64, error) {
+	return readValue(log, ctx, cm, 4, addr, var4, format)
+}
+
+// Read4UInt16 считывает входной регистр var4
+func Read4UInt16(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, var4 Var, byteOrder binary.ByteOrder) (uint16, error) {
+	return readUInt16(log, ctx, cm, 4, addr, var4, byteOrder)
+}
@@ -0,0 +1,57 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm/comport"
+	"testing"
+)
+
+func TestRead4(t *testing.T) {
+	dev := newMockDevice(1)
+	// 1.5 и -2 в формате float_big_endian
+	dev.input[10], dev.input[11] = 0x3FC0, 0x0000
+	dev.input[12], dev.input[13] = 0xC000, 0x0000
+	dev.input[20] = 0x1234
+	dev.holding[10], dev.holding[11] = 0x4000, 0
+	cm := dev.comm()
+	ctx := context.Background()
+
+	values, err := Read4Values(nil, ctx, cm, 1, 10, 2, FloatBigEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || values[0] != 1.5 || values[1] != -2 {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	v, err := Read4Value(nil, ctx, cm, 1, 12, FloatBigEndian)
+	if err != nil || v != -2 {
+		t.Errorf("unexpected value %v: %v", v, err)
+	}
+
+	n, err := Read4UInt16(nil, ctx, cm, 1, 20, binary.BigEndian)
+	if err != nil || n != 0x1234 {
+		t.Errorf("unexpected value %X: %v", n, err)
+	}
+
+	// функция 3 считывает регистры хранения
+	v, err = Read3Value(nil, ctx, cm, 1, 10, FloatBigEndian)
+	if err != nil || v != 2 {
+		t.Errorf("unexpected value %v: %v", v, err)
+	}
+
+	_, err = Read4UInt16(nil, ctx, cm, 1, 21, binary.BigEndian)
+	if code, ok := ExceptionCode(err); !ok || code != 2 {
+		t.Errorf("exception 2 expected: %v", err)
+	}
+
+	// ответ неверной длины
+	cm = cm.WithReadWriter(comport.NewMockPort(func([]byte) []byte {
+		return Request{Addr: 1, ProtoCmd: 4, Data: []byte{2, 0, 0}}.Bytes()
+	}))
+	if _, err = Read4Value(nil, ctx, cm, 1, 10, FloatBigEndian); err == nil || merry.Is(err, ErrException) {
+		t.Errorf("length error expected: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/internal"
+)
+
+const (
+	maxReadWrite23ReadCount  = 125 // наибольшее количество считываемых регистров в запросе функции 23
+	maxReadWrite23WriteCount = 121 // наибольшее количество записываемых регистров в запросе функции 23
+)
+
+// RequestReadWrite23 - запрос записи и последующего считывания регистров хранения за одну транзакцию, функция 23.
+// WriteData содержит значения записываемых регистров, по два байта на регистр, старший байт первым
+type RequestReadWrite23 struct {
+	Addr               Addr
+	ReadFirstRegister  Var
+	ReadRegistersCount uint16
+	WriteFirstRegister Var
+	WriteData          []byte
+}
+
+// RequestMaskWrite22 - запрос изменения регистра хранения по маскам, функция 22.
+// Новое значение регистра равно (текущее AND AndMask) OR (OrMask AND NOT AndMask)
+type RequestMaskWrite22 struct {
+	Addr     Addr
+	Register Var
+	AndMask  uint16
+	OrMask   uint16
+}
+
+func (x RequestReadWrite23) Request() Request {
+	n := len(x.WriteData) / 2
+	r := Request{
+		Addr:     x.Addr,
+		ProtoCmd: 23,
+		Data: []byte{
+			byte(x.ReadFirstRegister >> 8),
+			byte(x.ReadFirstRegister),
+			byte(x.ReadRegistersCount >> 8),
+			byte(x.ReadRegistersCount),
+			byte(x.WriteFirstRegister >> 8),
+			byte(x.WriteFirstRegister),
+			byte(n >> 8),
+			byte(n),
+			byte(n * 2),
+		},
+	}
+	r.Data = append(r.Data, x.WriteData[:n*2]...)
+	return r
+}
+
+// GetResponse возвращает ответ, проверив количество байт считанных регистров
+func (x RequestReadWrite23) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) ([]byte, error) {
+	wrapErr := func(err error) error {
+		return merry.Appendf(err, "запись модбас %d, %d, считывание модбас %d, %d",
+			x.WriteFirstRegister, len(x.WriteData)/2, x.ReadFirstRegister, x.ReadRegistersCount)
+	}
+	if len(x.WriteData) == 0 || len(x.WriteData)%2 != 0 {
+		return nil, wrapErr(merry.Errorf("длина данных должна быть чётной и больше нуля: % X", x.WriteData))
+	}
+	if err := checkQuantity(len(x.WriteData)/2, maxReadWrite23WriteCount); err != nil {
+		return nil, wrapErr(merry.Prepend(err, "запись"))
+	}
+	if err := checkQuantity(int(x.ReadRegistersCount), maxReadWrite23ReadCount); err != nil {
+		return nil, wrapErr(merry.Prepend(err, "считывание"))
+	}
+	log = internal.LogPrependSuffixKeys(log,
+		LogKeyRegsCount, x.ReadRegistersCount,
+		LogKeyFirstReg, x.ReadFirstRegister,
+	)
+	cm = cm.WithAppendParse(func(request, response []byte) error {
+		lenMustBe := int(x.ReadRegistersCount)*2 + 5
+		if len(response) != lenMustBe {
+			return merry.Errorf("ожидалось %d байт ответа, получено %d", lenMustBe, len(response))
+		}
+		if int(response[2]) != int(x.ReadRegistersCount)*2 {
+			return Err.Here().Appendf("ожидалось %d байт данных, в ответе указано %d",
+				x.ReadRegistersCount*2, response[2])
+		}
+		return nil
+	})
+	b, err := x.Request().GetResponse(log, ctx, cm)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return b, nil
+}
+
+// ReadWrite23UInt16Values записывает значения регистров хранения, начиная с writeFirst,
+// и затем считывает readCount регистров, начиная с readFirst, за одну транзакцию
+func ReadWrite23UInt16Values(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, readFirst Var, readCount int, writeFirst Var, values []uint16) ([]uint16, error) {
+	if err := checkQuantity(readCount, maxReadWrite23ReadCount); err != nil {
+		return nil, merry.Appendf(err, "считывание модбас %d, %d", readFirst, readCount)
+	}
+	data := make([]byte, 0, len(values)*2)
+	for _, v := range values {
+		data = append(data, byte(v>>8), byte(v))
+	}
+	response, err := RequestReadWrite23{
+		Addr:               addr,
+		ReadFirstRegister:  readFirst,
+		ReadRegistersCount: uint16(readCount),
+		WriteFirstRegister: writeFirst,
+		WriteData:          data,
+	}.GetResponse(log, ctx, cm)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint16, readCount)
+	for i := range result {
+		result[i] = uint16(response[3+i*2])<<8 | uint16(response[4+i*2])
+	}
+	return result, nil
+}
+
+func (x RequestMaskWrite22) Request() Request {
+	return Request{
+		Addr:     x.Addr,
+		ProtoCmd: 22,
+		Data: []byte{
+			byte(x.Register >> 8),
+			byte(x.Register),
+			byte(x.AndMask >> 8),
+			byte(x.AndMask),
+			byte(x.OrMask >> 8),
+			byte(x.OrMask),
+		},
+	}
+}
+
+// GetResponse проверяет, что ответ повторяет запрос
+func (x RequestMaskWrite22) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) error {
+	log = internal.LogPrependSuffixKeys(log, LogKeyFirstReg, x.Register)
+	req := x.Request()
+	response, err := req.GetResponse(log, ctx, cm)
+	if err == nil && !bytes.Equal(req.Bytes(), response) {
+		err = Err.Here().Appendf("ошибка формата: ответ % X не повторяет запрос % X", response, req.Bytes())
+	}
+	return merry.Appendf(err, "запись регистра %d по маскам AND %04X OR %04X", x.Register, x.AndMask, x.OrMask)
+}
+
+// SetRegisterBits устанавливает в регистре хранения биты mask, не изменяя остальные биты
+func SetRegisterBits(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, register Var, mask uint16) error {
+	return RequestMaskWrite22{Addr: addr, Register: register, AndMask: ^mask, OrMask: mask}.GetResponse(log, ctx, cm)
+}
+
+// ClearRegisterBits сбрасывает в регистре хранения биты mask, не изменяя остальные биты
+func ClearRegisterBits(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, register Var, mask uint16) error {
+	return RequestMaskWrite22{Addr: addr, Register: register, AndMask: ^mask}.GetResponse(log, ctx, cm)
+}
@@ -0,0 +1,89 @@
+package modbus
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm/comport"
+	"reflect"
+	"testing"
+)
+
+func TestReadWrite23(t *testing.T) {
+	dev := newMockDevice(1)
+	for i := Var(0); i < 4; i++ {
+		dev.holding[i] = uint16(i)
+	}
+	cm := dev.comm()
+	ctx := context.Background()
+
+	// считывание выполняется после записи
+	got, err := ReadWrite23UInt16Values(nil, ctx, cm, 1, 0, 4, 2, []uint16{0xAA, 0xBB})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint16{0, 1, 0xAA, 0xBB}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %X, want %X", got, want)
+	}
+
+	cm = comport.NewMock(func([]byte) []byte {
+		return Request{Addr: 1, ProtoCmd: 23, Data: []byte{4, 0, 1, 0, 2}}.Bytes()
+	})
+	if _, err := ReadWrite23UInt16Values(nil, ctx, cm, 1, 0, 1, 0, []uint16{1}); err == nil {
+		t.Error("length error expected")
+	}
+
+	cm = comport.NewMock(func(req []byte) []byte {
+		t.Errorf("request must not be sent: % X", req)
+		return nil
+	})
+	for _, x := range []struct {
+		readCount  int
+		writeCount int
+	}{
+		{0, 1},
+		{126, 1},
+		{0x10001, 1},
+		{1, 122},
+		{1, 128},
+	} {
+		if _, err := ReadWrite23UInt16Values(nil, ctx, cm, 1, 0, x.readCount, 0, make([]uint16, x.writeCount)); err == nil {
+			t.Errorf("read %d, write %d: error expected", x.readCount, x.writeCount)
+		}
+	}
+}
+
+func TestMaskWrite22(t *testing.T) {
+	dev := newMockDevice(1)
+	dev.holding[7] = 0x00F0
+	cm := dev.comm()
+	ctx := context.Background()
+
+	if err := SetRegisterBits(nil, ctx, cm, 1, 7, 0x0101); err != nil {
+		t.Fatal(err)
+	}
+	if v := dev.holding[7]; v != 0x01F1 {
+		t.Errorf("got %04X, want 01F1", v)
+	}
+	if err := ClearRegisterBits(nil, ctx, cm, 1, 7, 0x0030); err != nil {
+		t.Fatal(err)
+	}
+	if v := dev.holding[7]; v != 0x01C1 {
+		t.Errorf("got %04X, want 01C1", v)
+	}
+	// пример из спецификации модбас: 0x12, AND 0xF2, OR 0x25 => 0x17
+	dev.holding[4] = 0x12
+	err := RequestMaskWrite22{Addr: 1, Register: 4, AndMask: 0xF2, OrMask: 0x25}.GetResponse(nil, ctx, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := dev.holding[4]; v != 0x17 {
+		t.Errorf("got %04X, want 0017", v)
+	}
+
+	cm = comport.NewMock(func([]byte) []byte {
+		return RequestMaskWrite22{Addr: 1, Register: 7, AndMask: 0xFFFF}.Request().Bytes()
+	})
+	if err := SetRegisterBits(nil, ctx, cm, 1, 7, 1); !merry.Is(err, Err) {
+		t.Errorf("modbus error expected: %v", err)
+	}
+}
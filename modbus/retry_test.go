@@ -0,0 +1,42 @@
+package modbus
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"testing"
+	"time"
+)
+
+func TestRetryOn(t *testing.T) {
+	for _, x := range []struct {
+		name          string
+		response      []byte
+		err           error
+		requestsCount int
+	}{
+		{"exception", Request{Addr: 1, ProtoCmd: 0x83, Data: []byte{2}}.Bytes(), ErrException, 1},
+		{"crc", []byte{1, 3, 2, 0, 0, 0, 0}, ErrCRC16, 3},
+	} {
+		requestsCount := 0
+		cm := comport.NewMock(func([]byte) []byte {
+			requestsCount++
+			return x.response
+		}).WithConfig(comm.Config{
+			TimeoutGetResponse: time.Second,
+			MaxAttemptsRead:    3,
+		}).WithRetryPolicy(comm.Retry{RetryOn: RetryOn})
+
+		_, err := Read3UInt16(nil, context.Background(), cm, 1, 0, nil)
+		if !merry.Is(err, x.err) {
+			t.Errorf("%s: unexpected error %v", x.name, err)
+		}
+		if requestsCount != x.requestsCount {
+			t.Errorf("%s: %d requests, expected %d", x.name, requestsCount, x.requestsCount)
+		}
+		if code, ok := ExceptionCode(err); ok != (x.err == ErrException) || ok && code != 2 {
+			t.Errorf("%s: exception code %d, %v", x.name, code, ok)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package modbus
+
+import (
+	"context"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"testing"
+)
+
+func TestStatsErrorKinds(t *testing.T) {
+	comm.ResetStats()
+	for _, response := range [][]byte{
+		Request{Addr: 2, ProtoCmd: 0x83, Data: []byte{2}}.Bytes(),
+		{2, 3, 2, 0, 0, 0, 0},
+	} {
+		response := response
+		cm := comport.NewMock(func([]byte) []byte {
+			return response
+		}).WithLockPort("TestStatsErrorKinds")
+		if _, err := Read3UInt16(nil, context.Background(), cm, 2, 0, nil); err == nil {
+			t.Fatal("error expected")
+		}
+	}
+	c := comm.Stats()["TestStatsErrorKinds"].Devices[comm.DeviceKey{Device: "2", Function: "3"}]
+	if c.Transactions != 2 || c.Exceptions != 1 || c.CRCErrors != 1 {
+		t.Errorf("unexpected stats %+v", c)
+	}
+}
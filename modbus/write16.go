@@ -0,0 +1,126 @@
+package modbus
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/internal"
+)
+
+// maxWriteRegistersCount - наибольшее количество регистров в запросе функции 16
+const maxWriteRegistersCount = 123
+
+// RequestWrite6 - запрос записи регистра хранения, функция 6
+type RequestWrite6 struct {
+	Addr     Addr
+	Register Var
+	Value    uint16
+}
+
+// RequestWrite16 - запрос записи нескольких регистров хранения, функция 16.
+// Data содержит значения регистров, по два байта на регистр, старший байт первым
+type RequestWrite16 struct {
+	Addr          Addr
+	FirstRegister Var
+	Data          []byte
+}
+
+func (x RequestWrite6) Request() Request {
+	return Request{
+		Addr:     x.Addr,
+		ProtoCmd: 6,
+		Data: []byte{
+			byte(x.Register >> 8),
+			byte(x.Register),
+			byte(x.Value >> 8),
+			byte(x.Value),
+		},
+	}
+}
+
+// GetResponse проверяет, что ответ повторяет адрес и значение регистра
+func (x RequestWrite6) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) error {
+	log = internal.LogPrependSuffixKeys(log, LogKeyFirstReg, x.Register)
+	req := x.Request()
+	response, err := req.GetResponse(log, ctx, cm)
+	if err == nil {
+		err = checkEcho(req.Bytes(), response)
+	}
+	return merry.Appendf(err, "запись регистра %d=%d", x.Register, x.Value)
+}
+
+func (x RequestWrite16) RegistersCount() int {
+	return len(x.Data) / 2
+}
+
+func (x RequestWrite16) Request() Request {
+	n := x.RegistersCount()
+	r := Request{
+		Addr:     x.Addr,
+		ProtoCmd: 16,
+		Data: []byte{
+			byte(x.FirstRegister >> 8),
+			byte(x.FirstRegister),
+			byte(n >> 8),
+			byte(n),
+			byte(n * 2),
+		},
+	}
+	r.Data = append(r.Data, x.Data[:n*2]...)
+	return r
+}
+
+// GetResponse проверяет, что ответ повторяет адрес первого регистра и количество регистров
+func (x RequestWrite16) GetResponse(log comm.Logger, ctx context.Context, cm comm.T) error {
+	wrapErr := func(err error) error {
+		return merry.Appendf(err, "запись модбас %d, %d", x.FirstRegister, x.RegistersCount())
+	}
+	if len(x.Data) == 0 || len(x.Data)%2 != 0 {
+		return wrapErr(merry.Errorf("длина данных должна быть чётной и больше нуля: % X", x.Data))
+	}
+	if err := checkQuantity(x.RegistersCount(), maxWriteRegistersCount); err != nil {
+		return wrapErr(err)
+	}
+	log = internal.LogPrependSuffixKeys(log,
+		LogKeyRegsCount, x.RegistersCount(),
+		LogKeyFirstReg, x.FirstRegister,
+	)
+	req := x.Request()
+	response, err := req.GetResponse(log, ctx, cm)
+	if err == nil {
+		err = checkEcho(req.Bytes(), response)
+	}
+	return wrapErr(err)
+}
+
+// Write6 записывает значение регистра хранения функцией 6
+func Write6(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, register Var, value uint16) error {
+	return RequestWrite6{Addr: addr, Register: register, Value: value}.GetResponse(log, ctx, cm)
+}
+
+// Write16UInt16Values записывает значения регистров хранения, начиная с first, функцией 16
+func Write16UInt16Values(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, first Var, values []uint16) error {
+	data := make([]byte, 0, len(values)*2)
+	for _, v := range values {
+		data = append(data, byte(v>>8), byte(v))
+	}
+	return RequestWrite16{Addr: addr, FirstRegister: first, Data: data}.GetResponse(log, ctx, cm)
+}
+
+// Write16Values записывает значения в формате format, по два регистра хранения на значение,
+// начиная с first, функцией 16
+func Write16Values(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, first Var, format FloatBitsFormat, values []float64) error {
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		if err := format.PutFloat(data[i*4:], v); err != nil {
+			return merry.Appendf(err, "запись %d параметров %s, параметр %d", len(values), format, i)
+		}
+	}
+	err := RequestWrite16{Addr: addr, FirstRegister: first, Data: data}.GetResponse(log, ctx, cm)
+	return merry.Appendf(err, "запись %d параметров %s", len(values), format)
+}
+
+// Write16Value записывает значение в формате format в регистры хранения first, first+1 функцией 16
+func Write16Value(log comm.Logger, ctx context.Context, cm comm.T, addr Addr, first Var, format FloatBitsFormat, value float64) error {
+	return Write16Values(log, ctx, cm, addr, first, format, []float64{value})
+}
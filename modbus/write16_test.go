@@ -0,0 +1,79 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/fpawel/comm"
+	"testing"
+)
+
+func TestWriteRegisters(t *testing.T) {
+	dev := newMockDevice(1)
+	cm := dev.comm()
+	ctx := context.Background()
+
+	if err := Write6(nil, ctx, cm, 1, 5, 0xABCD); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Read3UInt16(nil, ctx, cm, 1, 5, binary.BigEndian); err != nil || n != 0xABCD {
+		t.Errorf("unexpected value %X: %v", n, err)
+	}
+
+	if err := Write16UInt16Values(nil, ctx, cm, 1, 10, []uint16{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []uint16{1, 2, 3} {
+		if v := dev.holding[10+Var(i)]; v != want {
+			t.Errorf("register %d: %d, want %d", 10+i, v, want)
+		}
+	}
+
+	for _, format := range []FloatBitsFormat{BCD, FloatBigEndian, FloatLittleEndian, IntBigEndian, IntLittleEndian} {
+		if err := Write16Values(nil, ctx, cm, 1, 20, format, []float64{12, -3}); err != nil {
+			t.Fatal(err)
+		}
+		values, err := Read3Values(nil, ctx, cm, 1, 20, 2, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values[0] != 12 || values[1] != -3 {
+			t.Errorf("%s: unexpected values %v", format, values)
+		}
+	}
+	if err := Write16Value(nil, ctx, cm, 1, 30, FloatBigEndian, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := Read3Value(nil, ctx, cm, 1, 30, FloatBigEndian); err != nil || v != 1.5 {
+		t.Errorf("unexpected value %v: %v", v, err)
+	}
+
+	if err := Write16UInt16Values(nil, ctx, cm, 1, 10, nil); err == nil {
+		t.Error("error expected for empty values")
+	}
+	for _, count := range []int{124, 128} {
+		if err := Write16UInt16Values(nil, ctx, cm, 1, 10, make([]uint16, count)); err == nil {
+			t.Errorf("error expected for %d registers", count)
+		}
+	}
+}
+
+func TestWriteRegistersValidateResponse(t *testing.T) {
+	ctx := context.Background()
+	testInvalidResponses(t, []invalidResponse{
+		{
+			"write6 value",
+			Request{Addr: 1, ProtoCmd: 6, Data: []byte{0, 5, 0, 1}}.Bytes(),
+			func(cm comm.T) error { return Write6(nil, ctx, cm, 1, 5, 2) },
+		},
+		{
+			"write16 address",
+			Request{Addr: 1, ProtoCmd: 16, Data: []byte{0, 6, 0, 1}}.Bytes(),
+			func(cm comm.T) error { return Write16UInt16Values(nil, ctx, cm, 1, 5, []uint16{1}) },
+		},
+		{
+			"write16 quantity",
+			Request{Addr: 1, ProtoCmd: 16, Data: []byte{0, 5, 0, 1}}.Bytes(),
+			func(cm comm.T) error { return Write16UInt16Values(nil, ctx, cm, 1, 5, []uint16{1, 2}) },
+		},
+	})
+}
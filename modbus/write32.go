@@ -50,13 +50,19 @@ func (x RequestWrite32) GetResponse(log comm.Logger, ctx context.Context, cm com
 	if err != nil {
 		return wrapErr(err)
 	}
-	request := req.Bytes()
-	for i := 2; i < 6; i++ {
-		if request[i] != response[i] {
-			err = merry.Appendf(Err.Here(),
-				"ошибка формата: запрос[2:6]==[% X] != ответ[2:6]==[% X]", request[2:6], response[2:6])
-			return wrapErr(err)
-		}
+	if err := checkEcho(req.Bytes(), response); err != nil {
+		return wrapErr(err)
 	}
 	return nil
 }
+
+// Send отправляет запись в прибор, не ожидая ответа. Используется для широковещательной записи с адресом AddrBroadcast
+func (x RequestWrite32) Send(log comm.Logger, ctx context.Context, cm comm.T) error {
+	log = internal.LogPrependSuffixKeys(log,
+		LogKeyDeviceCmd, x.DeviceCmd,
+		LogKeyDeviceCmdArg, x.Value,
+		"float_bits_format", x.Format,
+	)
+	err := x.Request().Send(log, ctx, cm)
+	return merry.Appendf(err, "запись в прибор (команда=%d аргумент=%v %s)", x.DeviceCmd, x.Value, x.Format)
+}
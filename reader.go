@@ -0,0 +1,209 @@
+package comm
+
+import (
+	"github.com/ansel1/merry"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// AvailableReader - порт, сообщающий количество принятых байт, которые могут быть считаны без блокировки.
+// Реализуется comport.Port. Для порта, не реализующего AvailableReader, T использует таймаут чтения
+// DeadlineReader, а если и он не поддерживается - фоновую горутину чтения
+type AvailableReader interface {
+	io.Reader
+	// Available возвращает количество принятых байт, доступных для чтения
+	Available() (int, error)
+}
+
+// DeadlineReader - порт с таймаутом чтения, например net.Conn или os.File
+type DeadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// responseReader считывает ответ из порта способом, который этот порт поддерживает
+type responseReader interface {
+	// discard отбрасывает байты, принятые до отправки запроса
+	discard() error
+	// read возвращает принятые байты, ожидая их не дольше timeout.
+	// Если байты не приняты, возвращает nil, nil
+	read(done <-chan struct{}, timeout time.Duration) ([]byte, error)
+}
+
+func newResponseReader(r io.Reader) (responseReader, error) {
+	switch r := r.(type) {
+	case AvailableReader:
+		return availableReader{r}, nil
+	case DeadlineReader:
+		// os.File, не поддерживающий таймауты, возвращает ошибку
+		if err := r.SetReadDeadline(time.Time{}); err == nil {
+			return deadlineReader{r}, nil
+		}
+	}
+	return getBackgroundReader(r)
+}
+
+const readPollInterval = time.Millisecond
+
+type availableReader struct {
+	r AvailableReader
+}
+
+func (x availableReader) discard() error {
+	for {
+		n, err := x.r.Available()
+		if err != nil {
+			return merry.Wrap(err)
+		}
+		if n == 0 {
+			return nil
+		}
+		if _, err := x.r.Read(make([]byte, n)); err != nil {
+			return merry.Wrap(err)
+		}
+	}
+}
+
+func (x availableReader) read(done <-chan struct{}, timeout time.Duration) ([]byte, error) {
+	bytesToReadCount, err := x.r.Available()
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+	if bytesToReadCount == 0 {
+		pause(done, timeout)
+		return nil, nil
+	}
+	b := make([]byte, bytesToReadCount)
+	readCount, err := x.r.Read(b)
+	if err != nil {
+		return nil, merry.Wrap(err)
+	}
+	if readCount != bytesToReadCount {
+		return nil, merry.Errorf("считано %d байт из %d: % X", readCount, bytesToReadCount, b[:readCount])
+	}
+	return b, nil
+}
+
+type deadlineReader struct {
+	r DeadlineReader
+}
+
+func (x deadlineReader) discard() error {
+	// истёкший таймаут чтения net.Conn возвращает ошибку, не считывая принятые байты
+	for {
+		b, err := x.readUntil(time.Now().Add(readPollInterval))
+		if err != nil || len(b) == 0 {
+			return err
+		}
+	}
+}
+
+func (x deadlineReader) read(_ <-chan struct{}, timeout time.Duration) ([]byte, error) {
+	return x.readUntil(time.Now().Add(timeout))
+}
+
+func (x deadlineReader) readUntil(deadline time.Time) ([]byte, error) {
+	if err := x.r.SetReadDeadline(deadline); err != nil {
+		return nil, merry.Wrap(err)
+	}
+	b := make([]byte, 256)
+	n, err := x.r.Read(b)
+	if err != nil && !isTimeout(err) {
+		return b[:n], merry.Wrap(err)
+	}
+	return b[:n], nil
+}
+
+func isTimeout(err error) bool {
+	e, ok := err.(interface{ Timeout() bool })
+	return ok && e.Timeout()
+}
+
+// backgroundReader считывает данные из блокирующего порта в отдельной горутине.
+// На каждый порт создаётся не более одной горутины, которая завершается при ошибке чтения порта,
+// например, после его закрытия
+type backgroundReader struct {
+	r      io.Reader
+	mu     sync.Mutex
+	buf    []byte
+	err    error
+	signal chan struct{}
+}
+
+func getBackgroundReader(r io.Reader) (*backgroundReader, error) {
+	if !reflect.TypeOf(r).Comparable() {
+		return nil, merry.Errorf("порт %T должен реализовывать comm.AvailableReader либо быть указателем", r)
+	}
+	x := &backgroundReader{
+		r:      r,
+		signal: make(chan struct{}, 1),
+	}
+	o, loaded := backgroundReaders.LoadOrStore(r, x)
+	if !loaded {
+		go x.run()
+	}
+	return o.(*backgroundReader), nil
+}
+
+func (x *backgroundReader) run() {
+	b := make([]byte, 256)
+	for {
+		n, err := x.r.Read(b)
+		x.mu.Lock()
+		x.buf = append(x.buf, b[:n]...)
+		if err != nil {
+			x.err = err
+			backgroundReaders.Delete(x.r)
+		}
+		x.mu.Unlock()
+		select {
+		case x.signal <- struct{}{}:
+		default:
+		}
+		if err != nil {
+			return
+		}
+		if n == 0 {
+			// порт с таймаутом чтения, например comport.Port
+			time.Sleep(readPollInterval)
+		}
+	}
+}
+
+func (x *backgroundReader) discard() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.buf = nil
+	return nil
+}
+
+func (x *backgroundReader) read(done <-chan struct{}, timeout time.Duration) ([]byte, error) {
+	if b, err := x.take(); len(b) > 0 || err != nil {
+		return b, err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-x.signal:
+		return x.take()
+	case <-timer.C:
+		return nil, nil
+	case <-done:
+		return nil, nil
+	}
+}
+
+func (x *backgroundReader) take() ([]byte, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	b := x.buf
+	x.buf = nil
+	if len(b) > 0 || x.err == nil {
+		return b, nil
+	}
+	return nil, merry.Wrap(x.err)
+}
+
+var backgroundReaders sync.Map
@@ -0,0 +1,134 @@
+package comm_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/fpawel/comm"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDeadlineReader(t *testing.T) {
+	conn, device := net.Pipe()
+	defer conn.Close()
+	defer device.Close()
+	go serveEcho(device, device)
+
+	testPlainReadWriter(t, conn)
+}
+
+func TestDeadlineReaderDiscardsLateResponse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		device, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer device.Close()
+		b := make([]byte, 256)
+		for {
+			n, err := device.Read(b)
+			if err != nil {
+				return
+			}
+			if b[0] == 0xEE {
+				// ответ после истечения таймаута ожидания ответа
+				time.Sleep(100 * time.Millisecond)
+			}
+			if _, err := device.Write(b[:n]); err != nil {
+				return
+			}
+		}
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	cm := comm.New(conn, comm.Config{
+		TimeoutGetResponse: 50 * time.Millisecond,
+		TimeoutEndResponse: 20 * time.Millisecond,
+	})
+	if _, err := cm.GetResponse(nil, context.Background(), []byte{0xEE}); err == nil {
+		t.Fatal("timeout expected")
+	}
+	time.Sleep(100 * time.Millisecond)
+	request := []byte{1, 2, 3, 4}
+	response, err := cm.GetResponse(nil, context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(request, response) {
+		t.Fatalf("% X, expected % X", response, request)
+	}
+}
+
+func TestAvailableReaderDiscardsStaleBytes(t *testing.T) {
+	port := new(pushPort)
+	cm := comm.New(port, comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 20 * time.Millisecond,
+	})
+	// байты, оставшиеся от предыдущей транзакции
+	port.push(0xEE, 0xEE)
+	request := []byte{1, 2}
+	response, err := cm.GetResponse(nil, context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(request, response) {
+		t.Fatalf("% X, expected % X", response, request)
+	}
+}
+
+func TestBackgroundReader(t *testing.T) {
+	requestReader, requestWriter := io.Pipe()
+	responseReader, responseWriter := io.Pipe()
+	defer requestWriter.Close()
+	defer responseWriter.Close()
+	go serveEcho(requestReader, responseWriter)
+
+	testPlainReadWriter(t, &struct {
+		io.Reader
+		io.Writer
+	}{responseReader, requestWriter})
+}
+
+func testPlainReadWriter(t *testing.T, rw io.ReadWriter) {
+	t.Helper()
+	cm := comm.New(rw, comm.Config{
+		TimeoutGetResponse: time.Second,
+		TimeoutEndResponse: 20 * time.Millisecond,
+	})
+	for i := byte(0); i < 10; i++ {
+		request := []byte{i, 1, 2, 3}
+		response, err := cm.GetResponse(nil, context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(request, response) {
+			t.Fatalf("% X, expected % X", response, request)
+		}
+	}
+}
+
+// serveEcho отвечает на каждый запрос его копией
+func serveEcho(r io.Reader, w io.Writer) {
+	b := make([]byte, 256)
+	for {
+		n, err := r.Read(b)
+		if err != nil {
+			return
+		}
+		if _, err := w.Write(b[:n]); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package comm
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ansel1/merry"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recorder записывает каждую попытку приёмопередачи в файл в формате JSON Lines.
+// При превышении размера файла maxSize файл переименовывается в filename.1,
+// предыдущие копии сдвигаются, копии с номером больше maxBackups удаляются
+type Recorder struct {
+	filename   string
+	maxSize    int64
+	maxBackups int
+	mu         sync.Mutex
+	f          *os.File
+	size       int64
+	err        error
+}
+
+// NewRecorder открывает файл записи filename для добавления.
+// Если maxSize <= 0, размер файла не ограничен
+func NewRecorder(filename string, maxSize int64, maxBackups int) (*Recorder, error) {
+	x := &Recorder{
+		filename:   filename,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := x.open(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Record записывает попытку приёмопередачи i. Ошибка записи сохраняется и возвращается методом Err
+func (x *Recorder) Record(i Info) {
+	b, err := json.Marshal(newRecord(i))
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err != nil {
+		x.err = merry.Wrap(err)
+		return
+	}
+	b = append(b, '\n')
+	if x.f == nil {
+		return
+	}
+	if x.maxSize > 0 && x.size > 0 && x.size+int64(len(b)) > x.maxSize {
+		if err := x.rotate(); err != nil {
+			x.err = err
+			return
+		}
+	}
+	n, err := x.f.Write(b)
+	x.size += int64(n)
+	if err != nil {
+		x.err = merry.Wrap(err)
+	}
+}
+
+// Err возвращает последнюю ошибку записи
+func (x *Recorder) Err() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.err
+}
+
+func (x *Recorder) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.f == nil {
+		return nil
+	}
+	err := x.f.Close()
+	x.f = nil
+	return merry.Wrap(err)
+}
+
+func (x *Recorder) open() error {
+	f, err := os.OpenFile(x.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return merry.Wrap(err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return merry.Wrap(err)
+	}
+	x.f = f
+	x.size = st.Size()
+	return nil
+}
+
+func (x *Recorder) rotate() error {
+	if err := x.f.Close(); err != nil {
+		return merry.Wrap(err)
+	}
+	x.f = nil
+	if x.maxBackups < 1 {
+		if err := os.Remove(x.filename); err != nil {
+			return merry.Wrap(err)
+		}
+		return x.open()
+	}
+	_ = os.Remove(backupFilename(x.filename, x.maxBackups))
+	for n := x.maxBackups - 1; n > 0; n-- {
+		err := os.Rename(backupFilename(x.filename, n), backupFilename(x.filename, n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return merry.Wrap(err)
+		}
+	}
+	if err := os.Rename(x.filename, backupFilename(x.filename, 1)); err != nil {
+		return merry.Wrap(err)
+	}
+	return x.open()
+}
+
+func backupFilename(filename string, n int) string {
+	return fmt.Sprintf("%s.%d", filename, n)
+}
+
+// ReadRecording считывает попытки приёмопередачи, записанные Recorder
+func ReadRecording(r io.Reader) ([]Info, error) {
+	var xs []Info
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return xs, merry.Prependf(err, "строка %d", line)
+		}
+		i, err := rec.info()
+		if err != nil {
+			return xs, merry.Prependf(err, "строка %d", line)
+		}
+		xs = append(xs, i)
+	}
+	return xs, merry.Wrap(scanner.Err())
+}
+
+// ReadRecordingFile считывает попытки приёмопередачи из файла filename и его копий filename.N,
+// созданных Recorder, в хронологическом порядке
+func ReadRecordingFile(filename string) ([]Info, error) {
+	var filenames []string
+	for n := 1; ; n++ {
+		if _, err := os.Stat(backupFilename(filename, n)); err != nil {
+			break
+		}
+		filenames = append([]string{backupFilename(filename, n)}, filenames...)
+	}
+	filenames = append(filenames, filename)
+
+	var xs []Info
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return xs, merry.Wrap(err)
+		}
+		ys, err := ReadRecording(f)
+		_ = f.Close()
+		xs = append(xs, ys...)
+		if err != nil {
+			return xs, merry.Prepend(err, filename)
+		}
+	}
+	return xs, nil
+}
+
+// record - строка файла записи
+type record struct {
+	Time     time.Time `json:"time"`
+	Port     string    `json:"port"`
+	Attempt  int       `json:"attempt"`
+	Request  string    `json:"request"`
+	Response string    `json:"response"`
+	Duration string    `json:"duration"`
+	Err      string    `json:"error,omitempty"`
+	Timeout  bool      `json:"timeout,omitempty"`
+}
+
+func newRecord(i Info) record {
+	r := record{
+		Time:     i.Time,
+		Port:     i.Port,
+		Attempt:  i.Attempt,
+		Request:  fmt.Sprintf("% X", i.Request),
+		Response: fmt.Sprintf("% X", i.Response),
+		Duration: i.Duration.String(),
+	}
+	if i.Err != nil {
+		r.Err = i.Err.Error()
+		r.Timeout = merry.Is(i.Err, context.DeadlineExceeded)
+	}
+	return r
+}
+
+func (x record) info() (Info, error) {
+	i := Info{
+		Time:    x.Time,
+		Port:    x.Port,
+		Attempt: x.Attempt,
+	}
+	var err error
+	if i.Request, err = parseHexBytes(x.Request); err != nil {
+		return i, merry.Prepend(err, "запрос")
+	}
+	if i.Response, err = parseHexBytes(x.Response); err != nil {
+		return i, merry.Prepend(err, "ответ")
+	}
+	if i.Duration, err = time.ParseDuration(x.Duration); err != nil {
+		return i, merry.Prepend(err, "длительность")
+	}
+	switch {
+	case x.Timeout:
+		i.Err = merry.WithMessage(context.DeadlineExceeded, x.Err)
+	case len(x.Err) > 0:
+		i.Err = merry.New(x.Err)
+	}
+	return i, nil
+}
+
+func parseHexBytes(s string) ([]byte, error) {
+	return hex.DecodeString(strings.Replace(s, " ", "", -1))
+}
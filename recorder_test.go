@@ -0,0 +1,132 @@
+package comm_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "comm-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "traffic.jsonl")
+	rec, err := comm.NewRecorder(filename, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newEchoMock().WithRecorder(rec).GetResponse(nil, context.Background(), []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	cm := comm.New(silentPort{}, comm.Config{
+		TimeoutGetResponse: time.Millisecond,
+		MaxAttemptsRead:    2,
+	}).WithRecorder(rec)
+	if _, err := cm.GetResponse(nil, context.Background(), []byte{4}); !merry.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("timeout expected: %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	xs, err := comm.ReadRecordingFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xs) != 3 {
+		t.Fatalf("3 records expected, got %d", len(xs))
+	}
+	if !bytes.Equal(xs[0].Request, []byte{1, 2, 3}) || !bytes.Equal(xs[0].Response, []byte{1, 2, 3}) || xs[0].Err != nil {
+		t.Errorf("unexpected record %+v", xs[0])
+	}
+	if xs[0].Time.IsZero() || xs[0].Duration <= 0 {
+		t.Errorf("time and duration expected: %+v", xs[0])
+	}
+	for i, x := range xs[1:] {
+		if !bytes.Equal(x.Request, []byte{4}) || len(x.Response) != 0 || x.Attempt != i {
+			t.Errorf("unexpected record %+v", x)
+		}
+		if !merry.Is(x.Err, context.DeadlineExceeded) {
+			t.Errorf("timeout expected: %v", x.Err)
+		}
+	}
+}
+
+func TestRecorderRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "comm-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "traffic.jsonl")
+	rec, err := comm.NewRecorder(filename, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 10; n++ {
+		rec.Record(comm.Info{
+			Time:    time.Now(),
+			Request: []byte{byte(n)},
+		})
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filename, filename + ".1", filename + ".2"} {
+		st, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Size() > 200 {
+			t.Errorf("%s: size %d exceeds limit", name, st.Size())
+		}
+	}
+	if _, err := os.Stat(filename + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond limit: %v", err)
+	}
+
+	xs, err := comm.ReadRecordingFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xs) == 0 || len(xs) >= 10 {
+		t.Fatalf("unexpected records count %d", len(xs))
+	}
+	// сохраняются последние записи в хронологическом порядке
+	for i, x := range xs {
+		if int(x.Request[0]) != 10-len(xs)+i {
+			t.Errorf("record %d: request % X", i, x.Request)
+		}
+	}
+}
+
+func TestRecorderMarshalError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "comm-recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rec, err := comm.NewRecorder(filepath.Join(dir, "traffic.jsonl"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Close()
+
+	// время вне диапазона JSON не прерывает приёмопередачу
+	rec.Record(comm.Info{Time: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if rec.Err() == nil {
+		t.Error("error expected")
+	}
+}
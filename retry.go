@@ -0,0 +1,113 @@
+package comm
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"math/rand"
+	"time"
+)
+
+// Attempt содержит результат неудачной попытки получения ответа
+type Attempt struct {
+	Number   int           // номер попытки, начиная с 0
+	Response []byte        // ответ
+	Err      error         // ошибка
+	Duration time.Duration // время ожидания ответа
+}
+
+// RetryPolicy определяет, следует ли повторить запрос после неудачной попытки.
+// Число попыток ограничено Config.MaxAttemptsRead
+type RetryPolicy interface {
+	// Retry получает историю неудачных попыток, последняя попытка - последний элемент history.
+	// Возвращает признак повтора запроса и паузу перед повтором
+	Retry(request []byte, history []Attempt) (retry bool, delay time.Duration)
+}
+
+// RetryPolicyFunc - функция, реализующая RetryPolicy
+type RetryPolicyFunc func(request []byte, history []Attempt) (bool, time.Duration)
+
+func (f RetryPolicyFunc) Retry(request []byte, history []Attempt) (bool, time.Duration) {
+	return f(request, history)
+}
+
+// Backoff возвращает паузу перед повтором по количеству неудачных попыток failedCount
+type Backoff = func(failedCount int) time.Duration
+
+// RetryClassifier возвращает true для ошибок, после которых запрос следует повторить
+type RetryClassifier = func(err error) bool
+
+// Retry - RetryPolicy, повторяющая запрос после ошибок, для которых RetryOn возвращает true,
+// с паузой Backoff
+type Retry struct {
+	Backoff Backoff         // если nil, повтор без паузы
+	RetryOn RetryClassifier // если nil, используется IsRetryable
+}
+
+func (x Retry) Retry(_ []byte, history []Attempt) (bool, time.Duration) {
+	retryOn := x.RetryOn
+	if retryOn == nil {
+		retryOn = IsRetryable
+	}
+	if len(history) == 0 || !retryOn(history[len(history)-1].Err) {
+		return false, 0
+	}
+	if x.Backoff == nil {
+		return true, 0
+	}
+	return true, x.Backoff(len(history))
+}
+
+// IsRetryable возвращает true для ошибок протокола Err и таймаутов ожидания ответа
+func IsRetryable(err error) bool {
+	return merry.Is(err, Err) || merry.Is(err, context.DeadlineExceeded)
+}
+
+// ConstantBackoff возвращает Backoff с постоянной паузой d
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// LinearBackoff возвращает Backoff с паузой initial + step*(failedCount-1)
+func LinearBackoff(initial, step time.Duration) Backoff {
+	return func(failedCount int) time.Duration {
+		return initial + step*time.Duration(failedCount-1)
+	}
+}
+
+// ExponentialBackoff возвращает Backoff с паузой initial*2^(failedCount-1), не превышающей maxDelay.
+// Пауза случайным образом уменьшается не более чем на долю jitter от 0 до 1
+func ExponentialBackoff(initial, maxDelay time.Duration, jitter float64) Backoff {
+	return func(failedCount int) time.Duration {
+		d := initial
+		for i := 1; i < failedCount && d < maxDelay; i++ {
+			d *= 2
+		}
+		if d > maxDelay {
+			d = maxDelay
+		}
+		if jitter > 0 {
+			d -= time.Duration(rand.Float64() * jitter * float64(d))
+		}
+		return d
+	}
+}
+
+// defaultRetryPolicy повторяет запрос после таймаута без паузы,
+// а после ошибки протокола Err - с паузой Config.TimeoutEndResponse
+type defaultRetryPolicy struct {
+	pause time.Duration
+}
+
+func (x defaultRetryPolicy) Retry(_ []byte, history []Attempt) (bool, time.Duration) {
+	err := history[len(history)-1].Err
+	switch {
+	case merry.Is(err, context.DeadlineExceeded):
+		return true, 0
+	case merry.Is(err, Err):
+		return true, x.pause
+	default:
+		return false, 0
+	}
+}
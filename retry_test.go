@@ -0,0 +1,82 @@
+package comm_test
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for _, x := range []struct {
+		name    string
+		backoff comm.Backoff
+		delays  []time.Duration
+	}{
+		{"constant", comm.ConstantBackoff(10), []time.Duration{10, 10, 10}},
+		{"linear", comm.LinearBackoff(10, 5), []time.Duration{10, 15, 20}},
+		{"exponential", comm.ExponentialBackoff(10, 50, 0), []time.Duration{10, 20, 40, 50, 50}},
+	} {
+		for i, d := range x.delays {
+			if v := x.backoff(i + 1); v != d {
+				t.Errorf("%s: failed %d: %v, expected %v", x.name, i+1, v, d)
+			}
+		}
+	}
+	backoff := comm.ExponentialBackoff(100, 1000, 0.5)
+	for i := 0; i < 100; i++ {
+		if v := backoff(2); v < 100 || v > 200 {
+			t.Fatalf("jitter: %v", v)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var (
+		requestsCount int
+		history       []comm.Attempt
+		errPermanent  = merry.New("permanent")
+	)
+	cm := comport.NewMock(func(req []byte) []byte {
+		requestsCount++
+		return req
+	}).WithConfig(comm.Config{
+		TimeoutGetResponse: time.Second,
+		MaxAttemptsRead:    5,
+	}).WithAppendParse(func(request, response []byte) error {
+		if requestsCount == 3 {
+			return errPermanent.Here()
+		}
+		return comm.Err.Here()
+	}).WithRetryPolicy(comm.RetryPolicyFunc(func(request []byte, h []comm.Attempt) (bool, time.Duration) {
+		history = h
+		return comm.Retry{Backoff: comm.ConstantBackoff(time.Millisecond)}.Retry(request, h)
+	}))
+
+	_, err := cm.GetResponse(nil, context.Background(), []byte{1})
+	if !merry.Is(err, errPermanent) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requestsCount != 3 {
+		t.Errorf("%d requests, expected 3", requestsCount)
+	}
+	if len(history) != 3 {
+		t.Fatalf("%d attempts in history, expected 3", len(history))
+	}
+	for i, a := range history {
+		if a.Number != i {
+			t.Errorf("attempt %d: number %d", i, a.Number)
+		}
+	}
+	if !merry.Is(history[0].Err, comm.Err) || !merry.Is(history[2].Err, errPermanent) {
+		t.Errorf("unexpected history: %+v", history)
+	}
+
+	requestsCount = 0
+	_, err = cm.WithRetryPolicy(comm.Retry{}).GetResponse(nil, context.Background(), []byte{1})
+	if !merry.Is(err, errPermanent) || requestsCount != 3 {
+		t.Errorf("%d requests: %v", requestsCount, err)
+	}
+}
@@ -0,0 +1,250 @@
+package comm
+
+import (
+	"context"
+	"github.com/ansel1/merry"
+	"sync"
+	"time"
+)
+
+// Priority - приоритет транзакции при ожидании порта, захваченного WithLockPort.
+// Транзакция с большим приоритетом получает порт раньше
+type Priority int
+
+const (
+	PriorityLow    Priority = -10
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 10
+)
+
+// PriorityAging - время ожидания порта, за которое приоритет транзакции увеличивается на единицу.
+// Ограничивает время ожидания транзакций с низким приоритетом
+var PriorityAging = 100 * time.Millisecond
+
+// ContextWithPriority возвращает контекст, задающий приоритет транзакции.
+// Приоритет контекста имеет преимущество перед приоритетом T.WithPriority
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext возвращает приоритет, заданный ContextWithPriority
+func PriorityFromContext(ctx context.Context) (Priority, bool) {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	return p, ok
+}
+
+// QueueStats - состояние очереди транзакций порта
+type QueueStats struct {
+	Busy      bool          // порт захвачен транзакцией
+	Depth     int           // количество транзакций, ожидающих порт
+	MaxDepth  int           // наибольшее количество транзакций, ожидавших порт
+	Acquired  uint64        // количество захватов порта
+	Canceled  uint64        // количество транзакций, не дождавшихся порта
+	TotalWait time.Duration // суммарное время ожидания порта
+	MaxWait   time.Duration // наибольшее время ожидания порта
+}
+
+// PortQueueStats возвращает состояние очереди транзакций порта port
+func PortQueueStats(port string) QueueStats {
+	o, ok := lockPorts.Load(port)
+	if !ok {
+		return QueueStats{}
+	}
+	return o.(*scheduler).stats()
+}
+
+// PortsQueueStats возвращает состояние очередей транзакций всех портов, захватывавшихся WithLockPort
+func PortsQueueStats() map[string]QueueStats {
+	r := make(map[string]QueueStats)
+	lockPorts.Range(func(k, v interface{}) bool {
+		r[k.(string)] = v.(*scheduler).stats()
+		return true
+	})
+	return r
+}
+
+type priorityKey struct{}
+
+// scheduler предоставляет порт транзакциям в порядке приоритета,
+// при равном приоритете - в порядке очереди.
+// Порт, не нужный транзакциям, предоставляется Listener, см. acquireIdle
+type scheduler struct {
+	mu        sync.Mutex
+	busy      bool
+	listening bool // порт захвачен Listener
+	queue     []*schedulerWaiter
+	idle      []*schedulerWaiter // Listener, ожидающие освобождения порта
+	yield     context.CancelFunc // требует от Listener освободить порт
+	seq       uint64
+	st        QueueStats
+}
+
+type schedulerWaiter struct {
+	priority Priority
+	seq      uint64
+	t        time.Time
+	ready    chan struct{}
+	yield    context.CancelFunc
+}
+
+func (x *scheduler) acquire(ctx context.Context, priority Priority) (time.Duration, error) {
+	t := time.Now()
+	x.mu.Lock()
+	if !x.busy && len(x.queue) == 0 {
+		x.busy = true
+		x.st.Acquired++
+		x.mu.Unlock()
+		return 0, nil
+	}
+	x.seq++
+	w := &schedulerWaiter{
+		priority: priority,
+		seq:      x.seq,
+		t:        t,
+		ready:    make(chan struct{}),
+	}
+	x.queue = append(x.queue, w)
+	if x.yield != nil {
+		x.yield()
+		x.yield = nil
+	}
+	if len(x.queue) > x.st.MaxDepth {
+		x.st.MaxDepth = len(x.queue)
+	}
+	x.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return time.Since(t), nil
+	case <-ctx.Done():
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	select {
+	case <-w.ready:
+		// порт предоставлен одновременно с отменой ожидания
+		return time.Since(t), nil
+	default:
+	}
+	for i := range x.queue {
+		if x.queue[i] == w {
+			x.queue = append(x.queue[:i], x.queue[i+1:]...)
+			break
+		}
+	}
+	x.st.Canceled++
+	return time.Since(t), ctx.Err()
+}
+
+// acquireIdle захватывает порт для Listener, когда порт не ожидает ни одна транзакция.
+// Захват не учитывается в QueueStats. Возвращаемый контекст отменяется,
+// как только порт начинает ожидать транзакция, после чего порт должен быть освобождён
+func (x *scheduler) acquireIdle(ctx context.Context) (context.Context, error) {
+	holdCtx, cancel := context.WithCancel(ctx)
+	x.mu.Lock()
+	if !x.busy && len(x.queue) == 0 {
+		x.busy = true
+		x.listening = true
+		x.yield = cancel
+		x.mu.Unlock()
+		return holdCtx, nil
+	}
+	w := &schedulerWaiter{
+		ready: make(chan struct{}),
+		yield: cancel,
+	}
+	x.idle = append(x.idle, w)
+	x.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return holdCtx, nil
+	case <-ctx.Done():
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	select {
+	case <-w.ready:
+		// порт предоставлен одновременно с отменой ожидания
+		return holdCtx, nil
+	default:
+	}
+	for i := range x.idle {
+		if x.idle[i] == w {
+			x.idle = append(x.idle[:i], x.idle[i+1:]...)
+			break
+		}
+	}
+	cancel()
+	return nil, ctx.Err()
+}
+
+func (x *scheduler) release() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.busy {
+		return merry.New("освобождение не захваченного порта")
+	}
+	if x.yield != nil {
+		x.yield()
+		x.yield = nil
+	}
+	x.listening = false
+	if len(x.queue) == 0 && len(x.idle) > 0 {
+		w := x.idle[0]
+		x.idle = x.idle[1:]
+		x.listening = true
+		x.yield = w.yield
+		close(w.ready)
+		return nil
+	}
+	if len(x.queue) == 0 {
+		x.busy = false
+		return nil
+	}
+	now := time.Now()
+	n := 0
+	for i := 1; i < len(x.queue); i++ {
+		if x.queue[i].before(x.queue[n], now) {
+			n = i
+		}
+	}
+	w := x.queue[n]
+	x.queue = append(x.queue[:n], x.queue[n+1:]...)
+
+	wait := now.Sub(w.t)
+	x.st.Acquired++
+	x.st.TotalWait += wait
+	if wait > x.st.MaxWait {
+		x.st.MaxWait = wait
+	}
+	close(w.ready)
+	return nil
+}
+
+func (x *scheduler) stats() QueueStats {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	st := x.st
+	st.Busy = x.busy && !x.listening
+	st.Depth = len(x.queue)
+	return st
+}
+
+// before возвращает true, если w должен получить порт раньше other
+func (w *schedulerWaiter) before(other *schedulerWaiter, now time.Time) bool {
+	p1, p2 := w.effectivePriority(now), other.effectivePriority(now)
+	if p1 != p2 {
+		return p1 > p2
+	}
+	return w.seq < other.seq
+}
+
+func (w *schedulerWaiter) effectivePriority(now time.Time) Priority {
+	if PriorityAging <= 0 {
+		return w.priority
+	}
+	return w.priority + Priority(now.Sub(w.t)/PriorityAging)
+}
@@ -0,0 +1,167 @@
+package comm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerPriority(t *testing.T) {
+	var x scheduler
+	if _, err := x.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan Priority, 3)
+	for i, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		p := p
+		go func() {
+			if _, err := x.acquire(context.Background(), p); err != nil {
+				t.Error(err)
+				return
+			}
+			order <- p
+		}()
+		waitSchedulerDepth(t, &x, i+1)
+	}
+	for _, expected := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		if err := x.release(); err != nil {
+			t.Fatal(err)
+		}
+		if p := <-order; p != expected {
+			t.Fatalf("priority %d acquired, expected %d", p, expected)
+		}
+	}
+	if err := x.release(); err != nil {
+		t.Fatal(err)
+	}
+	st := x.stats()
+	if st.Busy || st.Depth != 0 || st.MaxDepth != 3 || st.Acquired != 4 || st.MaxWait == 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if err := x.release(); err == nil {
+		t.Error("release of not acquired scheduler must fail")
+	}
+}
+
+func TestSchedulerAging(t *testing.T) {
+	defer func(d time.Duration) {
+		PriorityAging = d
+	}(PriorityAging)
+	PriorityAging = 5 * time.Millisecond
+
+	var x scheduler
+	if _, err := x.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan Priority, 2)
+	acquire := func(p Priority) {
+		if _, err := x.acquire(context.Background(), p); err != nil {
+			t.Error(err)
+			return
+		}
+		order <- p
+	}
+	go acquire(PriorityLow)
+	waitSchedulerDepth(t, &x, 1)
+
+	// ожидание дольше (PriorityHigh-PriorityLow)*PriorityAging поднимает приоритет выше PriorityHigh
+	time.Sleep(time.Duration(PriorityHigh-PriorityLow+1) * PriorityAging)
+	go acquire(PriorityHigh)
+	waitSchedulerDepth(t, &x, 2)
+
+	for _, expected := range []Priority{PriorityLow, PriorityHigh} {
+		if err := x.release(); err != nil {
+			t.Fatal(err)
+		}
+		if p := <-order; p != expected {
+			t.Fatalf("priority %d acquired, expected %d", p, expected)
+		}
+	}
+	if err := x.release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	var x scheduler
+	if _, err := x.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := x.acquire(ctx, PriorityHigh); err != context.DeadlineExceeded {
+		t.Fatalf("deadline expected: %v", err)
+	}
+	if st := x.stats(); st.Depth != 0 || st.Canceled != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if err := x.release(); err != nil {
+		t.Fatal(err)
+	}
+	if st := x.stats(); st.Busy {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestSchedulerIdle(t *testing.T) {
+	var x scheduler
+	holdCtx, err := x.acquireIdle(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := x.stats(); st.Busy || st.Acquired != 0 {
+		t.Errorf("idle acquisition must not be counted: %+v", st)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if _, err := x.acquire(context.Background(), PriorityLow); err != nil {
+			t.Error(err)
+		}
+		close(acquired)
+	}()
+	select {
+	case <-holdCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("idle holder must be asked to release")
+	}
+
+	// Listener ожидает окончания транзакции
+	idleAcquired := make(chan struct{})
+	go func() {
+		if _, err := x.acquireIdle(context.Background()); err != nil {
+			t.Error(err)
+		}
+		close(idleAcquired)
+	}()
+	if err := x.release(); err != nil {
+		t.Fatal(err)
+	}
+	<-acquired
+	select {
+	case <-idleAcquired:
+		t.Fatal("idle acquisition during transaction")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if err := x.release(); err != nil {
+		t.Fatal(err)
+	}
+	<-idleAcquired
+	if st := x.stats(); st.Busy || st.Acquired != 1 || st.Depth != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if err := x.release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitSchedulerDepth(t *testing.T, x *scheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for x.stats().Depth != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth %d, expected %d", x.stats().Depth, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,286 @@
+package comm
+
+import (
+	"context"
+	"fmt"
+	"github.com/ansel1/merry"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorKind - вид ошибки ответа, учитываемый в статистике
+type ErrorKind string
+
+const (
+	ErrorKindCRC       ErrorKind = "crc"       // несовпадение контрольной суммы ответа
+	ErrorKindException ErrorKind = "exception" // устройство вернуло код ошибки
+)
+
+// WithErrorKind помечает ошибку err видом kind для учёта в статистике
+func WithErrorKind(err error, kind ErrorKind) merry.Error {
+	return merry.WithValue(err, errorKindKey{}, kind)
+}
+
+// GetErrorKind возвращает вид ошибки, заданный WithErrorKind
+func GetErrorKind(err error) (ErrorKind, bool) {
+	kind, ok := merry.Value(err, errorKindKey{}).(ErrorKind)
+	return kind, ok
+}
+
+// ResponseTimeBuckets - верхние границы интервалов гистограммы времени ответа
+var ResponseTimeBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// Counters - счётчики приёмопередачи
+type Counters struct {
+	Transactions  uint64    `json:"transactions"`   // количество вызовов GetResponse
+	Failed        uint64    `json:"failed"`         // количество вызовов GetResponse, завершившихся ошибкой
+	Attempts      uint64    `json:"attempts"`       // количество попыток
+	Timeouts      uint64    `json:"timeouts"`       // количество попыток без ответа
+	CRCErrors     uint64    `json:"crc_errors"`     // количество ответов с ошибкой ErrorKindCRC
+	Exceptions    uint64    `json:"exceptions"`     // количество ответов с ошибкой ErrorKindException
+	Errors        uint64    `json:"errors"`         // количество попыток, завершившихся прочими ошибками
+	BytesSent     uint64    `json:"bytes_sent"`     // количество переданных байт
+	BytesReceived uint64    `json:"bytes_received"` // количество принятых байт
+	ResponseTime  Histogram `json:"response_time"`  // время ответа попыток, на которые был получен ответ
+}
+
+// Histogram - гистограмма длительностей
+type Histogram struct {
+	Bounds []time.Duration `json:"bounds"` // верхние границы интервалов
+	Counts []uint64        `json:"counts"` // количество значений в интервалах, последний - больше всех границ
+	Count  uint64          `json:"count"`  // количество значений
+	Sum    time.Duration   `json:"sum"`    // сумма значений
+}
+
+// DeviceKey - устройство и функция протокола, например адрес и код функции модбас
+type DeviceKey struct {
+	Device   string `json:"device"`
+	Function string `json:"function"`
+}
+
+func (x DeviceKey) MarshalText() ([]byte, error) {
+	return []byte(x.Device + "/" + x.Function), nil
+}
+
+// PortStats - статистика порта и подключенных к нему устройств
+type PortStats struct {
+	Counters
+	Devices map[DeviceKey]Counters `json:"devices"`
+}
+
+// Stats возвращает статистику приёмопередачи всех портов
+func Stats() map[string]PortStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	r := make(map[string]PortStats, len(stats))
+	for port, x := range stats {
+		p := PortStats{
+			Counters: x.Counters.clone(),
+			Devices:  make(map[DeviceKey]Counters, len(x.devices)),
+		}
+		for k, c := range x.devices {
+			p.Devices[k] = c.clone()
+		}
+		r[port] = p
+	}
+	return r
+}
+
+// ResetStats обнуляет статистику приёмопередачи
+func ResetStats() {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	stats = make(map[string]*portStats)
+}
+
+// StatsHandler возвращает http.Handler, выводящий статистику Stats в текстовом формате Prometheus
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WritePrometheus(w, Stats())
+	})
+}
+
+// WritePrometheus выводит статистику st в текстовом формате Prometheus
+func WritePrometheus(w io.Writer, st map[string]PortStats) error {
+	type series struct {
+		labels string
+		c      Counters
+	}
+	var ports, devices []series
+	for port, p := range st {
+		ports = append(ports, series{fmt.Sprintf(`port="%s"`, escapeLabel(port)), p.Counters})
+		for k, c := range p.Devices {
+			devices = append(devices, series{
+				fmt.Sprintf(`port="%s",device="%s",function="%s"`,
+					escapeLabel(port), escapeLabel(k.Device), escapeLabel(k.Function)),
+				c,
+			})
+		}
+	}
+	for _, xs := range [][]series{ports, devices} {
+		sort.Slice(xs, func(i, j int) bool {
+			return xs[i].labels < xs[j].labels
+		})
+	}
+
+	b := new(strings.Builder)
+	for _, m := range []struct {
+		prefix string
+		xs     []series
+	}{
+		{"comm_port", ports},
+		{"comm_device", devices},
+	} {
+		for _, counter := range []struct {
+			name, help string
+			value      func(Counters) uint64
+		}{
+			{"transactions_total", "Number of transactions.", func(c Counters) uint64 { return c.Transactions }},
+			{"failed_transactions_total", "Number of failed transactions.", func(c Counters) uint64 { return c.Failed }},
+			{"attempts_total", "Number of request attempts.", func(c Counters) uint64 { return c.Attempts }},
+			{"timeouts_total", "Number of attempts without response.", func(c Counters) uint64 { return c.Timeouts }},
+			{"crc_errors_total", "Number of responses with checksum mismatch.", func(c Counters) uint64 { return c.CRCErrors }},
+			{"exceptions_total", "Number of exception responses.", func(c Counters) uint64 { return c.Exceptions }},
+			{"errors_total", "Number of attempts failed with other errors.", func(c Counters) uint64 { return c.Errors }},
+			{"sent_bytes_total", "Number of bytes sent.", func(c Counters) uint64 { return c.BytesSent }},
+			{"received_bytes_total", "Number of bytes received.", func(c Counters) uint64 { return c.BytesReceived }},
+		} {
+			name := m.prefix + "_" + counter.name
+			fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, counter.help, name)
+			for _, x := range m.xs {
+				fmt.Fprintf(b, "%s{%s} %d\n", name, x.labels, counter.value(x.c))
+			}
+		}
+		name := m.prefix + "_response_time_seconds"
+		fmt.Fprintf(b, "# HELP %s Response time of attempts.\n# TYPE %s histogram\n", name, name)
+		for _, x := range m.xs {
+			h := x.c.ResponseTime
+			if h.Bounds == nil {
+				h.Bounds = ResponseTimeBuckets
+				h.Counts = make([]uint64, len(h.Bounds)+1)
+			}
+			var cumulative uint64
+			for i, bound := range h.Bounds {
+				cumulative += h.Counts[i]
+				fmt.Fprintf(b, "%s_bucket{%s,le=\"%g\"} %d\n", name, x.labels, bound.Seconds(), cumulative)
+			}
+			fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, x.labels, h.Count)
+			fmt.Fprintf(b, "%s_sum{%s} %g\n", name, x.labels, h.Sum.Seconds())
+			fmt.Fprintf(b, "%s_count{%s} %d\n", name, x.labels, h.Count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+type errorKindKey struct{}
+
+type portStats struct {
+	Counters
+	devices map[DeviceKey]Counters
+}
+
+func (x T) statsPort() string {
+	if s, f := x.rw.(fmt.Stringer); f {
+		return s.String()
+	}
+	return x.port
+}
+
+func (x T) recordStats(f func(c *Counters)) {
+	port := x.statsPort()
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	p := stats[port]
+	if p == nil {
+		p = &portStats{devices: make(map[DeviceKey]Counters)}
+		stats[port] = p
+	}
+	f(&p.Counters)
+	if x.statsDevice != (DeviceKey{}) {
+		c := p.devices[x.statsDevice]
+		f(&c)
+		p.devices[x.statsDevice] = c
+	}
+}
+
+func (x T) recordTransactionStats(err error) {
+	x.recordStats(func(c *Counters) {
+		c.Transactions++
+		if err != nil {
+			c.Failed++
+		}
+	})
+}
+
+func (x T) recordAttemptStats(request []byte, r result, duration time.Duration) {
+	x.recordStats(func(c *Counters) {
+		c.Attempts++
+		c.BytesSent += uint64(len(request))
+		c.BytesReceived += uint64(len(r.response))
+		if len(r.response) > 0 {
+			c.ResponseTime.add(duration)
+		}
+		if r.err == nil {
+			return
+		}
+		if kind, ok := GetErrorKind(r.err); ok {
+			switch kind {
+			case ErrorKindCRC:
+				c.CRCErrors++
+				return
+			case ErrorKindException:
+				c.Exceptions++
+				return
+			}
+		}
+		if len(r.response) == 0 && merry.Is(r.err, context.DeadlineExceeded) {
+			c.Timeouts++
+			return
+		}
+		c.Errors++
+	})
+}
+
+func (x *Histogram) add(d time.Duration) {
+	if x.Bounds == nil {
+		x.Bounds = append([]time.Duration(nil), ResponseTimeBuckets...)
+		x.Counts = make([]uint64, len(x.Bounds)+1)
+	}
+	i := sort.Search(len(x.Bounds), func(i int) bool {
+		return d <= x.Bounds[i]
+	})
+	x.Counts[i]++
+	x.Count++
+	x.Sum += d
+}
+
+func (x Counters) clone() Counters {
+	x.ResponseTime.Counts = append([]uint64(nil), x.ResponseTime.Counts...)
+	return x
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = make(map[string]*portStats)
+)
@@ -0,0 +1,82 @@
+package comm_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fpawel/comm"
+	"github.com/fpawel/comm/comport"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	comm.ResetStats()
+
+	errCRC := comm.WithErrorKind(comm.Err, comm.ErrorKindCRC)
+	responses := 0
+	cm := comport.NewMock(func(req []byte) []byte {
+		responses++
+		return []byte{1, 2, 3}
+	}).WithConfig(comm.Config{
+		TimeoutGetResponse: time.Second,
+		MaxAttemptsRead:    2,
+	}).WithLockPort("TestStats").WithStatsDevice("1", "3").WithAppendParse(func(request, response []byte) error {
+		if responses == 1 {
+			return errCRC.Here()
+		}
+		return nil
+	})
+	if _, err := cm.GetResponse(nil, context.Background(), []byte{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmTimeout := comm.New(silentPort{}, comm.Config{
+		TimeoutGetResponse: time.Millisecond,
+	}).WithLockPort("TestStats")
+	if _, err := cmTimeout.GetResponse(nil, context.Background(), []byte{1}); err == nil {
+		t.Fatal("timeout expected")
+	}
+
+	st := comm.Stats()["TestStats"]
+	for _, x := range []struct {
+		name      string
+		got, want uint64
+	}{
+		{"transactions", st.Transactions, 2},
+		{"failed", st.Failed, 1},
+		{"attempts", st.Attempts, 3},
+		{"crc", st.CRCErrors, 1},
+		{"timeouts", st.Timeouts, 1},
+		{"sent", st.BytesSent, 5},
+		{"received", st.BytesReceived, 6},
+		{"response time", st.ResponseTime.Count, 2},
+		{"device attempts", st.Devices[comm.DeviceKey{Device: "1", Function: "3"}].Attempts, 2},
+		{"device timeouts", st.Devices[comm.DeviceKey{Device: "1", Function: "3"}].Timeouts, 0},
+	} {
+		if x.got != x.want {
+			t.Errorf("%s: %d, expected %d", x.name, x.got, x.want)
+		}
+	}
+	if _, err := json.Marshal(comm.Stats()); err != nil {
+		t.Error(err)
+	}
+
+	w := httptest.NewRecorder()
+	comm.StatsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	text := w.Body.String()
+	for _, s := range []string{
+		"# TYPE comm_port_transactions_total counter\n",
+		`comm_port_transactions_total{port="TestStats"} 2` + "\n",
+		`comm_port_crc_errors_total{port="TestStats"} 1` + "\n",
+		`comm_device_attempts_total{port="TestStats",device="1",function="3"} 2` + "\n",
+		"# TYPE comm_port_response_time_seconds histogram\n",
+		`comm_port_response_time_seconds_bucket{port="TestStats",le="+Inf"} 2` + "\n",
+		`comm_port_response_time_seconds_count{port="TestStats"} 2` + "\n",
+	} {
+		if !strings.Contains(text, s) {
+			t.Errorf("%q expected in:\n%s", s, text)
+		}
+	}
+}